@@ -0,0 +1,85 @@
+package zapcloudlogging
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// WithAdaptiveLevel returns an Option that drops entries below WARNING while the rate of
+// entries at ERROR or above exceeds errRate, to protect the logging pipeline during incidents
+// that flood logs.
+//
+// The level is raised as soon as the rate in the current window exceeds errRate,
+// and is relaxed when a window of the given duration ends with the rate below errRate.
+func WithAdaptiveLevel(errRate float64, window time.Duration) Option {
+	return wrapCore(func(core zapcore.Core) zapcore.Core {
+		return &adaptiveCore{
+			Core: core,
+			state: &adaptiveState{
+				errRate: errRate,
+				window:  window,
+			},
+		}
+	})
+}
+
+type adaptiveCore struct {
+	zapcore.Core
+	state *adaptiveState
+}
+
+func (c *adaptiveCore) With(fields []zapcore.Field) zapcore.Core {
+	return &adaptiveCore{
+		Core:  c.Core.With(fields),
+		state: c.state,
+	}
+}
+
+func (c *adaptiveCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.state.observe(ent) && ent.Level < zapcore.WarnLevel {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
+
+// adaptiveState is the error rate shared by an adaptiveCore and its children.
+type adaptiveState struct {
+	errRate float64
+	window  time.Duration
+
+	mu     sync.Mutex
+	start  time.Time
+	total  int
+	errors int
+	raised bool
+}
+
+// observe counts ent, and reports whether the level is raised.
+func (s *adaptiveState) observe(ent zapcore.Entry) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if ent.Time.Sub(s.start) >= s.window {
+		s.raised = s.exceeded()
+		s.start = ent.Time
+		s.total = 0
+		s.errors = 0
+	}
+
+	s.total++
+	if ent.Level >= zapcore.ErrorLevel {
+		s.errors++
+	}
+	if s.exceeded() {
+		s.raised = true
+	}
+	return s.raised
+}
+
+// exceeded reports whether the error rate of the current window exceeds errRate.
+// s.mu must be held.
+func (s *adaptiveState) exceeded() bool {
+	return s.total > 0 && float64(s.errors)/float64(s.total) > s.errRate
+}
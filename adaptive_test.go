@@ -0,0 +1,91 @@
+package zapcloudlogging
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestAdaptiveState(t *testing.T) {
+	type step struct {
+		level      zapcore.Level
+		after      time.Duration
+		wantRaised bool
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "below rate",
+			steps: []step{
+				{level: zapcore.InfoLevel},
+				{level: zapcore.InfoLevel},
+				{level: zapcore.InfoLevel},
+				{level: zapcore.ErrorLevel},
+			},
+		},
+		{
+			name: "raised in window",
+			steps: []step{
+				{level: zapcore.InfoLevel},
+				{level: zapcore.ErrorLevel, wantRaised: true},
+				{level: zapcore.InfoLevel, wantRaised: true},
+				{level: zapcore.InfoLevel, wantRaised: true},
+			},
+		},
+		{
+			name: "kept after window over rate",
+			steps: []step{
+				{level: zapcore.ErrorLevel, wantRaised: true},
+				{level: zapcore.InfoLevel, after: time.Second, wantRaised: true},
+				{level: zapcore.InfoLevel, wantRaised: true},
+			},
+		},
+		{
+			name: "relaxed after window below rate",
+			steps: []step{
+				{level: zapcore.ErrorLevel, wantRaised: true},
+				{level: zapcore.InfoLevel, after: time.Second, wantRaised: true},
+				{level: zapcore.InfoLevel, wantRaised: true},
+				{level: zapcore.InfoLevel, wantRaised: true},
+				{level: zapcore.InfoLevel, after: time.Second},
+			},
+		},
+		{
+			name: "warning isn't an error",
+			steps: []step{
+				{level: zapcore.WarnLevel},
+				{level: zapcore.WarnLevel},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &adaptiveState{errRate: 0.4, window: time.Second}
+			now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+			for i, step := range tt.steps {
+				now = now.Add(step.after)
+				if got := s.observe(zapcore.Entry{Level: step.level, Time: now}); got != step.wantRaised {
+					t.Errorf("step %d: observe() = %v, want %v", i, got, step.wantRaised)
+				}
+			}
+		})
+	}
+}
+
+func TestWithAdaptiveLevel(t *testing.T) {
+	logger, sink := newTestLogger(t, WithAdaptiveLevel(0.5, time.Hour))
+	logger.Info("before")
+	logger.Error("error 1")
+	logger.Error("error 2")
+	logger.Info("dropped")
+	logger.Warn("warning")
+
+	var got []string
+	for _, e := range sink.entries(t) {
+		got = append(got, e[MessageKey].(string))
+	}
+	assertStrings(t, got, []string{"before", "error 1", "error 2", "warning"})
+}
@@ -0,0 +1,328 @@
+package zapcloudlogging
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	defaultBatchSize     = 1000
+	defaultFlushInterval = time.Second
+)
+
+// LogEntry is a log entry passed to an EntryWriter.
+//
+// https://cloud.google.com/logging/docs/reference/v2/rest/v2/LogEntry
+type LogEntry struct {
+	Timestamp time.Time
+	Severity  string
+	Caller    zapcore.EntryCaller
+	// Resource is the monitored resource of the entry, set by WithResource or Resource.
+	// If it's nil, the EntryWriter should use its default resource.
+	Resource *MonitoredResource
+	// Payload holds the message, the logger name, the stacktrace and the fields of the entry,
+	// keyed the same way as the structured logging output.
+	Payload map[string]interface{}
+}
+
+// An EntryWriter writes a batch of log entries, e.g. with the Cloud Logging API.
+type EntryWriter interface {
+	WriteEntries(ctx context.Context, entries []*LogEntry) error
+}
+
+type apiCoreOptions struct {
+	batchSize     int
+	flushInterval time.Duration
+	resource      *MonitoredResource
+	backpressure  Backpressure
+}
+
+// An APICoreOption configures a zapcore.Core created by NewAPICore.
+type APICoreOption func(*apiCoreOptions)
+
+// WithBatchSize sets the maximum number of entries buffered before they are written.
+// The default is 1000.
+func WithBatchSize(n int) APICoreOption {
+	return func(o *apiCoreOptions) {
+		o.batchSize = n
+	}
+}
+
+// WithFlushInterval sets the maximum duration an entry is buffered before it is written,
+// even if the batch is not full. The default is 1 second.
+func WithFlushInterval(d time.Duration) APICoreOption {
+	return func(o *apiCoreOptions) {
+		o.flushInterval = d
+	}
+}
+
+// Backpressure is the behavior of a core created by NewAPICore when the buffer is full,
+// that is, when a batch is filled while the previous batch is being written.
+type Backpressure int
+
+const (
+	// BackpressureDrop drops the entries while the buffer is full, counted by DroppedEntries.
+	// The goroutines logging while a batch is being written don't wait for it, at the cost of losing entries,
+	// but the goroutine whose entry fills a batch still calls WriteEntries synchronously,
+	// so that entry is as slow as the EntryWriter.
+	BackpressureDrop Backpressure = iota
+	// BackpressureBlock blocks logging while the buffer is full, until the previous batch is written.
+	// It never loses entries, at the cost of the latency of logging.
+	BackpressureBlock
+)
+
+// WithBackpressure sets the behavior when the buffer is full. The default is BackpressureDrop.
+func WithBackpressure(bp Backpressure) APICoreOption {
+	return func(o *apiCoreOptions) {
+		o.backpressure = bp
+	}
+}
+
+// droppedEntries is the number of the entries dropped by the cores created by NewAPICore.
+var droppedEntries uint64
+
+// DroppedEntries returns the number of the entries dropped by all the cores created by NewAPICore
+// with BackpressureDrop since the program started.
+func DroppedEntries() uint64 {
+	return atomic.LoadUint64(&droppedEntries)
+}
+
+// WithResource sets the monitored resource of the entries, e.g. the one returned by DetectResource.
+// By default, the resource is nil.
+func WithResource(mr *MonitoredResource) APICoreOption {
+	return func(o *apiCoreOptions) {
+		o.resource = mr
+	}
+}
+
+// resourceOverride is the value of a field created by Resource.
+type resourceOverride struct {
+	mr *MonitoredResource
+}
+
+// Resource returns a zap.Field that overrides the monitored resource of the entry written by NewAPICore,
+// e.g. for an entry about a Compute Engine instance managed by the service.
+// It's ignored by the other cores.
+func Resource(mr MonitoredResource) zap.Field {
+	return zap.Field{Type: zapcore.SkipType, Interface: resourceOverride{mr: &mr}}
+}
+
+// resourceOf returns the resource set by the last Resource field in fields.
+func resourceOf(fields []zapcore.Field) (*MonitoredResource, bool) {
+	for i := len(fields) - 1; i >= 0; i-- {
+		if r, ok := fields[i].Interface.(resourceOverride); ok && fields[i].Type == zapcore.SkipType {
+			return r.mr, true
+		}
+	}
+	return nil, false
+}
+
+// NewAPICore returns a zapcore.Core that buffers entries and writes them to w in batches.
+// A batch is written when it reaches the batch size or when the flush interval elapses,
+// whichever comes first. While a full batch is being written, the next batch is buffered,
+// and when it's also full, the entries are dropped or block logging, see WithBackpressure.
+// The batches are written one at a time, in order. Sync writes the buffered entries
+// after the batch being written, if any, so no entries are lost when the program exits after Sync.
+func NewAPICore(w EntryWriter, enab zapcore.LevelEnabler, opts ...APICoreOption) zapcore.Core {
+	o := apiCoreOptions{
+		batchSize:     defaultBatchSize,
+		flushInterval: defaultFlushInterval,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.batchSize <= 0 {
+		o.batchSize = defaultBatchSize
+	}
+	if o.flushInterval <= 0 {
+		o.flushInterval = defaultFlushInterval
+	}
+
+	b := &batcher{
+		w:             w,
+		batchSize:     o.batchSize,
+		flushInterval: o.flushInterval,
+		backpressure:  o.backpressure,
+	}
+	b.written = sync.NewCond(&b.mu)
+	return &apiCore{
+		LevelEnabler: enab,
+		resource:     o.resource,
+		batcher:      b,
+	}
+}
+
+type apiCore struct {
+	zapcore.LevelEnabler
+	fields   []zapcore.Field
+	resource *MonitoredResource
+	batcher  *batcher
+}
+
+func (c *apiCore) With(fields []zapcore.Field) zapcore.Core {
+	resource := c.resource
+	if mr, ok := resourceOf(fields); ok {
+		resource = mr
+	}
+	return &apiCore{
+		LevelEnabler: c.LevelEnabler,
+		fields:       append(c.fields[:len(c.fields):len(c.fields)], fields...),
+		resource:     resource,
+		batcher:      c.batcher,
+	}
+}
+
+func (c *apiCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *apiCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range c.fields {
+		f.AddTo(enc)
+	}
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	enc.Fields[encoderConfig.MessageKey] = ent.Message
+	if ent.LoggerName != "" {
+		enc.Fields[encoderConfig.NameKey] = ent.LoggerName
+	}
+	if ent.Stack != "" {
+		enc.Fields[encoderConfig.StacktraceKey] = ent.Stack
+	}
+
+	resource := c.resource
+	if mr, ok := resourceOf(fields); ok {
+		resource = mr
+	}
+
+	err := c.batcher.add(&LogEntry{
+		Timestamp: ent.Time,
+		Severity:  SeverityOf(ent.Level),
+		Caller:    ent.Caller,
+		Resource:  resource,
+		Payload:   enc.Fields,
+	})
+	if ent.Level > zapcore.ErrorLevel {
+		// Since we may be crashing the program, sync the output.
+		if serr := c.Sync(); err == nil {
+			err = serr
+		}
+	}
+	return err
+}
+
+func (c *apiCore) Sync() error {
+	return c.batcher.flush()
+}
+
+// batcher buffers entries shared by an apiCore and its children.
+// A batch is written by one goroutine at a time, so that the batches are written in order.
+type batcher struct {
+	w             EntryWriter
+	batchSize     int
+	flushInterval time.Duration
+	backpressure  Backpressure
+
+	mu      sync.Mutex
+	entries []*LogEntry
+	timer   *time.Timer
+	// writing reports whether a batch is being written.
+	writing bool
+	// written is signaled when a batch is written.
+	written *sync.Cond
+	// err is the error of the last write triggered by the timer,
+	// returned by the next call of add or flush.
+	err error
+}
+
+func (b *batcher) add(e *LogEntry) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for len(b.entries) >= b.batchSize && b.writing {
+		if b.backpressure == BackpressureDrop {
+			atomic.AddUint64(&droppedEntries, 1)
+			return nil
+		}
+		b.written.Wait()
+	}
+	b.entries = append(b.entries, e)
+	if len(b.entries) < b.batchSize || b.writing {
+		// A batch filled while another batch is being written is written by the writing goroutine.
+		if b.timer == nil {
+			b.timer = time.AfterFunc(b.flushInterval, b.flushByTimer)
+		}
+		err := b.err
+		b.err = nil
+		return err
+	}
+	return b.write(b.batchSize)
+}
+
+// flush writes all the buffered entries, after the batch being written by another goroutine, if any.
+func (b *batcher) flush() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.write(1)
+}
+
+func (b *batcher) flushByTimer() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	// The timer has fired, so it must not be stopped by write.
+	b.timer = nil
+	if err := b.write(1); err != nil {
+		b.err = err
+	}
+}
+
+// write waits for the batch being written by another goroutine, if any,
+// and then writes the buffered entries in batches while at least min entries are buffered,
+// including the entries buffered while writing.
+// It returns the first error, including the error of the last write triggered by the timer.
+// b.mu must be held, and it's released while writing.
+func (b *batcher) write(min int) error {
+	for b.writing {
+		b.written.Wait()
+	}
+	err := b.err
+	b.err = nil
+
+	for len(b.entries) > 0 && len(b.entries) >= min {
+		n := len(b.entries)
+		if n > b.batchSize {
+			n = b.batchSize
+		}
+		batch := b.entries[:n:n]
+		b.entries = append([]*LogEntry(nil), b.entries[n:]...)
+		if b.timer != nil {
+			b.timer.Stop()
+			b.timer = nil
+		}
+
+		b.writing = true
+		b.mu.Unlock()
+		werr := b.w.WriteEntries(context.Background(), batch)
+		b.mu.Lock()
+		b.writing = false
+		b.written.Broadcast()
+
+		if err == nil {
+			err = werr
+		}
+	}
+	if len(b.entries) > 0 && b.timer == nil {
+		b.timer = time.AfterFunc(b.flushInterval, b.flushByTimer)
+	}
+	return err
+}
@@ -0,0 +1,278 @@
+package zapcloudlogging
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// fakeEntryWriter is an EntryWriter recording the messages of the batches written to it.
+type fakeEntryWriter struct {
+	delay time.Duration
+	// started receives a value when WriteEntries is called, and WriteEntries returns after release is closed,
+	// if they're not nil.
+	started chan struct{}
+	release chan struct{}
+
+	mu      sync.Mutex
+	batches [][]string
+	// active is the number of the calls of WriteEntries in progress, and maxActive the maximum of it.
+	active    int
+	maxActive int
+}
+
+func (w *fakeEntryWriter) WriteEntries(ctx context.Context, entries []*LogEntry) error {
+	w.mu.Lock()
+	w.active++
+	if w.active > w.maxActive {
+		w.maxActive = w.active
+	}
+	w.mu.Unlock()
+
+	if w.started != nil {
+		w.started <- struct{}{}
+	}
+	if w.release != nil {
+		<-w.release
+	}
+	time.Sleep(w.delay)
+	batch := make([]string, len(entries))
+	for i, e := range entries {
+		batch[i] = e.Payload[MessageKey].(string)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.active--
+	w.batches = append(w.batches, batch)
+	return nil
+}
+
+func (w *fakeEntryWriter) written() [][]string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([][]string(nil), w.batches...)
+}
+
+func TestAPICore(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []APICoreOption
+		// delay is the delay of the writer.
+		delay time.Duration
+		// wait is the duration between logging and calling Sync.
+		wait        time.Duration
+		messages    []string
+		beforeSync  [][]string
+		afterSync   [][]string
+		maxParallel int
+	}{
+		{
+			name:       "batch size",
+			opts:       []APICoreOption{WithBatchSize(2), WithFlushInterval(time.Hour)},
+			messages:   []string{"a", "b", "c", "d", "e"},
+			beforeSync: [][]string{{"a", "b"}, {"c", "d"}},
+			afterSync:  [][]string{{"a", "b"}, {"c", "d"}, {"e"}},
+		},
+		{
+			name:       "flush interval",
+			opts:       []APICoreOption{WithBatchSize(100), WithFlushInterval(10 * time.Millisecond)},
+			wait:       100 * time.Millisecond,
+			messages:   []string{"a", "b"},
+			beforeSync: [][]string{{"a", "b"}},
+			afterSync:  [][]string{{"a", "b"}},
+		},
+		{
+			name:       "sync after timer",
+			opts:       []APICoreOption{WithBatchSize(100), WithFlushInterval(time.Millisecond)},
+			delay:      100 * time.Millisecond,
+			wait:       20 * time.Millisecond,
+			messages:   []string{"a"},
+			beforeSync: nil,
+			afterSync:  [][]string{{"a"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &fakeEntryWriter{delay: tt.delay}
+			logger := zap.New(NewAPICore(w, zapcore.DebugLevel, tt.opts...))
+			for _, msg := range tt.messages {
+				logger.Info(msg)
+			}
+			time.Sleep(tt.wait)
+
+			assertBatches(t, "before Sync", w.written(), tt.beforeSync)
+			if err := logger.Sync(); err != nil {
+				t.Fatalf("Sync() = %v", err)
+			}
+			assertBatches(t, "after Sync", w.written(), tt.afterSync)
+		})
+	}
+}
+
+func TestAPICoreWritesOneBatchAtATime(t *testing.T) {
+	w := &fakeEntryWriter{delay: 5 * time.Millisecond}
+	logger := zap.New(NewAPICore(w, zapcore.DebugLevel,
+		WithBatchSize(1), WithFlushInterval(time.Millisecond), WithBackpressure(BackpressureBlock)))
+
+	var want []string
+	for i := 0; i < 20; i++ {
+		msg := string(rune('a' + i))
+		logger.Info(msg)
+		want = append(want, msg)
+	}
+	if err := logger.Sync(); err != nil {
+		t.Fatalf("Sync() = %v", err)
+	}
+
+	var got []string
+	for _, batch := range w.written() {
+		got = append(got, batch...)
+	}
+	assertStrings(t, got, want)
+	if w.maxActive != 1 {
+		t.Errorf("WriteEntries was called %d times in parallel, want 1", w.maxActive)
+	}
+}
+
+func TestAPICoreBackpressure(t *testing.T) {
+	tests := []struct {
+		name         string
+		backpressure Backpressure
+		// wantBlocked reports whether logging waits for the batch being written when the buffer is full.
+		wantBlocked bool
+		want        [][]string
+		wantDropped uint64
+	}{
+		{name: "drop", backpressure: BackpressureDrop, want: [][]string{{"a"}, {"b"}}, wantDropped: 1},
+		{name: "block", backpressure: BackpressureBlock, wantBlocked: true, want: [][]string{{"a"}, {"b"}, {"c"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &fakeEntryWriter{started: make(chan struct{}, 3), release: make(chan struct{})}
+			logger := zap.New(NewAPICore(w, zapcore.DebugLevel,
+				WithBatchSize(1), WithFlushInterval(time.Hour), WithBackpressure(tt.backpressure)))
+			before := DroppedEntries()
+
+			aDone := make(chan struct{})
+			go func() {
+				defer close(aDone)
+				// "a" fills a batch, and this goroutine writes it.
+				logger.Info("a")
+			}()
+			<-w.started
+
+			// "b" fills the next batch while "a" is being written, and the buffer is full.
+			logger.Info("b")
+			cDone := make(chan struct{})
+			go func() {
+				defer close(cDone)
+				logger.Info("c")
+			}()
+
+			if tt.wantBlocked {
+				select {
+				case <-cDone:
+					t.Fatal("logging returned while the buffer was full")
+				default:
+				}
+			} else {
+				<-cDone
+			}
+			close(w.release)
+			<-aDone
+			<-cDone
+			if err := logger.Sync(); err != nil {
+				t.Fatalf("Sync() = %v", err)
+			}
+
+			if got := DroppedEntries() - before; got != tt.wantDropped {
+				t.Errorf("DroppedEntries() increased by %d, want %d", got, tt.wantDropped)
+			}
+			assertBatches(t, "after Sync", w.written(), tt.want)
+		})
+	}
+}
+
+func TestAPICoreResource(t *testing.T) {
+	def := &MonitoredResource{Type: "global"}
+	gce := MonitoredResource{Type: "gce_instance", Labels: map[string]string{"instance_id": "1"}}
+	tests := []struct {
+		name string
+		opts []APICoreOption
+		log  func(logger *zap.Logger)
+		want *MonitoredResource
+	}{
+		{name: "none", log: func(logger *zap.Logger) { logger.Info("msg") }},
+		{name: "default", opts: []APICoreOption{WithResource(def)}, log: func(logger *zap.Logger) { logger.Info("msg") }, want: def},
+		{
+			name: "field",
+			opts: []APICoreOption{WithResource(def)},
+			log:  func(logger *zap.Logger) { logger.Info("msg", Resource(gce)) },
+			want: &gce,
+		},
+		{
+			name: "with",
+			opts: []APICoreOption{WithResource(def)},
+			log:  func(logger *zap.Logger) { logger.With(Resource(gce)).Info("msg") },
+			want: &gce,
+		},
+		{
+			name: "last wins",
+			log: func(logger *zap.Logger) {
+				logger.With(Resource(MonitoredResource{Type: "k8s_container"})).Info("msg", Resource(gce))
+			},
+			want: &gce,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []*LogEntry
+			w := entryWriterFunc(func(ctx context.Context, entries []*LogEntry) error {
+				got = append(got, entries...)
+				return nil
+			})
+			logger := zap.New(NewAPICore(w, zapcore.DebugLevel, tt.opts...))
+			tt.log(logger)
+			if err := logger.Sync(); err != nil {
+				t.Fatalf("Sync() = %v", err)
+			}
+
+			if len(got) != 1 {
+				t.Fatalf("got %d entries, want 1", len(got))
+			}
+			assertJSON(t, "Resource", got[0].Resource, tt.want)
+			assertJSON(t, "Payload", got[0].Payload, map[string]any{MessageKey: "msg"})
+		})
+	}
+}
+
+type entryWriterFunc func(ctx context.Context, entries []*LogEntry) error
+
+func (f entryWriterFunc) WriteEntries(ctx context.Context, entries []*LogEntry) error {
+	return f(ctx, entries)
+}
+
+func assertBatches(t *testing.T, when string, got, want [][]string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Errorf("%s: batches = %q, want %q", when, got, want)
+		return
+	}
+	for i := range got {
+		if len(got[i]) != len(want[i]) {
+			t.Errorf("%s: batches = %q, want %q", when, got, want)
+			return
+		}
+		for j := range got[i] {
+			if got[i][j] != want[i][j] {
+				t.Errorf("%s: batches = %q, want %q", when, got, want)
+				return
+			}
+		}
+	}
+}
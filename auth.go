@@ -0,0 +1,71 @@
+package zapcloudlogging
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type authOutcome struct {
+	principal string
+	success   bool
+	method    string
+	reason    string
+}
+
+func (a authOutcome) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("principal", a.principal)
+	enc.AddBool("success", a.success)
+	enc.AddString("method", a.method)
+	if a.reason != "" {
+		enc.AddString("reason", a.reason)
+	}
+	return nil
+}
+
+type authOptions struct {
+	hashKey []byte
+}
+
+// An AuthOption configures the fields returned by AuthOutcome.
+type AuthOption func(*authOptions)
+
+// WithHashedPrincipal replaces the principal with the hex-encoded HMAC-SHA256 of it keyed with key,
+// so that the attempts of a principal can be correlated without logging who it is.
+// key should be a secret, otherwise the principals can be recovered by hashing the candidates.
+func WithHashedPrincipal(key []byte) AuthOption {
+	key = append([]byte{}, key...)
+	return func(o *authOptions) {
+		o.hashKey = key
+	}
+}
+
+// AuthOutcome returns the fields for an authentication of principal with method, e.g. "password" or "oidc",
+// and the reason of the outcome, e.g. "invalid_password":
+// method as the auth_method label, the outcome as the auth object,
+// and the severity, WARNING if it failed, otherwise INFO.
+// The reason is omitted if it's empty.
+func AuthOutcome(principal string, success bool, method string, reason string, opts ...AuthOption) []zap.Field {
+	var o authOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.hashKey != nil {
+		mac := hmac.New(sha256.New, o.hashKey)
+		mac.Write([]byte(principal))
+		principal = hex.EncodeToString(mac.Sum(nil))
+	}
+
+	level := zapcore.InfoLevel
+	if !success {
+		level = zapcore.WarnLevel
+	}
+	return []zap.Field{
+		Label("auth_method", method),
+		zap.Object("auth", authOutcome{principal: principal, success: success, method: method, reason: reason}),
+		Severity(level),
+	}
+}
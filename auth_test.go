@@ -0,0 +1,62 @@
+package zapcloudlogging
+
+import "testing"
+
+func TestAuthOutcome(t *testing.T) {
+	const fox = "The quick brown fox jumps over the lazy dog"
+	tests := []struct {
+		name          string
+		principal     string
+		success       bool
+		reason        string
+		opts          []AuthOption
+		wantPrincipal string
+		wantSeverity  string
+	}{
+		{name: "success", principal: "user@example.com", success: true, wantPrincipal: "user@example.com", wantSeverity: "INFO"},
+		{
+			name:          "failure",
+			principal:     "user@example.com",
+			reason:        "invalid_password",
+			wantPrincipal: "user@example.com",
+			wantSeverity:  "WARNING",
+		},
+		{
+			// The test vector of HMAC-SHA256 keyed with "key".
+			name:          "hashed",
+			principal:     fox,
+			success:       true,
+			opts:          []AuthOption{WithHashedPrincipal([]byte("key"))},
+			wantPrincipal: "f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8",
+			wantSeverity:  "INFO",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, sink := newTestLogger(t)
+			logger.Info("authenticated", AuthOutcome(tt.principal, tt.success, "password", tt.reason, tt.opts...)...)
+
+			got := sink.entries(t)[0]
+			assertJSON(t, SeverityKey, got[SeverityKey], tt.wantSeverity)
+			assertJSON(t, "labels", got[LabelsKey], map[string]string{"auth_method": "password"})
+			want := map[string]any{"principal": tt.wantPrincipal, "success": tt.success, "method": "password"}
+			if tt.reason != "" {
+				want["reason"] = tt.reason
+			}
+			assertJSON(t, "auth", got["auth"], want)
+		})
+	}
+
+	t.Run("key copied", func(t *testing.T) {
+		key := []byte("key")
+		opt := WithHashedPrincipal(key)
+		key[0] = 'K'
+
+		got := encodeFields(t, AuthOutcome(fox, true, "password", "", opt)...)
+		assertJSON(t, "auth", got["auth"], map[string]any{
+			"principal": "f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8",
+			"success":   true,
+			"method":    "password",
+		})
+	})
+}
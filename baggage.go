@@ -0,0 +1,42 @@
+package zapcloudlogging
+
+import (
+	"context"
+	"strings"
+
+	"go.opentelemetry.io/otel/baggage"
+	"go.uber.org/zap"
+)
+
+// labelKey returns s sanitized into a label key: letters are lowercased,
+// and characters other than letters, digits, underscores and hyphens are replaced with underscores.
+func labelKey(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case 'a' <= r && r <= 'z', '0' <= r && r <= '9', r == '_', r == '-':
+			return r
+		case 'A' <= r && r <= 'Z':
+			return r - 'A' + 'a'
+		default:
+			return '_'
+		}
+	}, s)
+}
+
+// LabelsFromBaggage returns a zap.Field that sets the members of the OpenTelemetry baggage in ctx as labels,
+// ordered by key, e.g. to propagate the tenant or a feature flag of a request to its entries.
+// The keys are lowercased, and characters other than letters, digits, underscores and hyphens
+// are replaced with underscores, e.g. "tenant.id" is set as "tenant_id".
+// If ctx carries no baggage, the field is skipped.
+func LabelsFromBaggage(ctx context.Context) zap.Field {
+	members := baggage.FromContext(ctx).Members()
+	if len(members) == 0 {
+		return zap.Skip()
+	}
+
+	m := make(map[string]string, len(members))
+	for _, member := range members {
+		m[labelKey(member.Key())] = member.Value()
+	}
+	return Labels(m)
+}
@@ -0,0 +1,40 @@
+package zapcloudlogging
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/baggage"
+)
+
+func TestLabelsFromBaggage(t *testing.T) {
+	tests := []struct {
+		name    string
+		baggage string
+		want    any
+	}{
+		{name: "no baggage", want: nil},
+		{name: "member", baggage: "tenant=acme", want: map[string]string{"tenant": "acme"}},
+		{
+			name:    "sanitized keys",
+			baggage: "tenant.id=acme,Feature-Flag=on,build#id=42",
+			want:    map[string]string{"tenant_id": "acme", "feature-flag": "on", "build_id": "42"},
+		},
+		{name: "escaped value", baggage: "region=asia%2Fnortheast", want: map[string]string{"region": "asia/northeast"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.baggage != "" {
+				b, err := baggage.Parse(tt.baggage)
+				if err != nil {
+					t.Fatal(err)
+				}
+				ctx = baggage.ContextWithBaggage(ctx, b)
+			}
+
+			got := encodeFields(t, LabelsFromBaggage(ctx))
+			assertJSON(t, "labels", got[LabelsKey], tt.want)
+		})
+	}
+}
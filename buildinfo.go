@@ -0,0 +1,29 @@
+package zapcloudlogging
+
+import "runtime/debug"
+
+// WithBuildInfo returns an Option that sets the VCS revision and time embedded in the binary
+// as the vcs_revision and vcs_time labels of every entry.
+// If the binary was built without VCS information, e.g. with -buildvcs=false, no labels are set.
+func WithBuildInfo() Option {
+	info, _ := debug.ReadBuildInfo()
+	return withLabels(buildInfoLabels(info))
+}
+
+// buildInfoLabels returns the labels for the VCS information of info.
+func buildInfoLabels(info *debug.BuildInfo) labels {
+	if info == nil {
+		return nil
+	}
+
+	var ls labels
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			ls = append(ls, label{key: "vcs_revision", value: s.Value})
+		case "vcs.time":
+			ls = append(ls, label{key: "vcs_time", value: s.Value})
+		}
+	}
+	return ls
+}
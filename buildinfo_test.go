@@ -0,0 +1,54 @@
+package zapcloudlogging
+
+import (
+	"runtime/debug"
+	"testing"
+)
+
+func TestBuildInfoLabels(t *testing.T) {
+	tests := []struct {
+		name string
+		info *debug.BuildInfo
+		want labels
+	}{
+		{name: "nil"},
+		{
+			name: "vcs",
+			info: &debug.BuildInfo{Settings: []debug.BuildSetting{
+				{Key: "-compiler", Value: "gc"},
+				{Key: "vcs.revision", Value: "0123abcd"},
+				{Key: "vcs.time", Value: "2024-01-02T03:04:05Z"},
+				{Key: "vcs.modified", Value: "false"},
+			}},
+			want: labels{
+				{key: "vcs_revision", value: "0123abcd"},
+				{key: "vcs_time", value: "2024-01-02T03:04:05Z"},
+			},
+		},
+		{
+			name: "without vcs",
+			info: &debug.BuildInfo{Settings: []debug.BuildSetting{{Key: "-compiler", Value: "gc"}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := buildInfoLabels(tt.info)
+			if len(got) != len(tt.want) {
+				t.Fatalf("buildInfoLabels() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("buildInfoLabels() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestWithBuildInfo(t *testing.T) {
+	// The test binary is built without VCS information, so it sets no labels.
+	logger, sink := newTestLogger(t, WithBuildInfo())
+	logger.Info("msg")
+
+	assertJSON(t, "entries", sink.entries(t), []map[string]any{{SeverityKey: "INFO", MessageKey: "msg"}})
+}
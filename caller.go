@@ -0,0 +1,66 @@
+package zapcloudlogging
+
+import (
+	"path"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// callerFile returns a function that returns the file path of a caller in mode, trimmed by trim if it's not nil.
+func callerFile(mode CallerMode, trim func(string) string) func(zapcore.EntryCaller) string {
+	var mainModule, mainPackage string
+	if info, ok := debug.ReadBuildInfo(); ok {
+		mainModule, mainPackage = info.Main.Path, info.Path
+	}
+
+	return func(caller zapcore.EntryCaller) string {
+		file := caller.File
+		switch mode {
+		case CallerModuleRelative:
+			file = moduleRelativePath(caller, mainModule, mainPackage)
+		case CallerBase:
+			file = filepath.Base(file)
+		}
+		if trim != nil {
+			file = trim(file)
+		}
+		return file
+	}
+}
+
+// moduleRelativePath returns the file path of caller relative to the root of mainModule,
+// determined by the import path of the package of the function.
+// If the package is not in mainModule, it returns the import path and the file name.
+func moduleRelativePath(caller zapcore.EntryCaller, mainModule, mainPackage string) string {
+	pkg := packagePath(caller.Function)
+	if pkg == "" {
+		return caller.File
+	}
+	if pkg == "main" && mainPackage != "" {
+		pkg = mainPackage
+	}
+
+	file := filepath.Base(caller.File)
+	switch {
+	case mainModule != "" && pkg == mainModule:
+		return file
+	case mainModule != "" && strings.HasPrefix(pkg, mainModule+"/"):
+		return path.Join(pkg[len(mainModule)+1:], file)
+	default:
+		return path.Join(pkg, file)
+	}
+}
+
+// packagePath returns the import path of the package of function,
+// e.g. "github.com/kechako/zapcloudlogging" for "github.com/kechako/zapcloudlogging.(*encoder).Clone".
+// The dots escaped by the runtime in the last element, e.g. "gopkg.in/yaml%2ev3", are unescaped.
+func packagePath(function string) string {
+	i := strings.LastIndexByte(function, '/')
+	if j := strings.IndexByte(function[i+1:], '.'); j >= 0 {
+		return strings.ReplaceAll(function[:i+1+j], "%2e", ".")
+	}
+	return ""
+}
@@ -0,0 +1,129 @@
+package zapcloudlogging
+
+import (
+	"strings"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestWithCallerMode(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []EncoderOption
+		want string
+	}{
+		{name: "default", want: "/home/user/src/app/db/conn.go"},
+		{name: "absolute", opts: []EncoderOption{WithCallerMode(CallerAbsolute)}, want: "/home/user/src/app/db/conn.go"},
+		{name: "base", opts: []EncoderOption{WithCallerMode(CallerBase)}, want: "conn.go"},
+		// The package of testCaller isn't in the main module of the test binary.
+		{name: "module relative", opts: []EncoderOption{WithCallerMode(CallerModuleRelative)}, want: "example.com/app/db/conn.go"},
+		{
+			name: "trimmed after formed",
+			opts: []EncoderOption{
+				WithCallerPathTrimmer(func(file string) string { return strings.TrimPrefix(file, "example.com/") }),
+				WithCallerMode(CallerModuleRelative),
+			},
+			want: "app/db/conn.go",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := encodeEntry(t, NewProductionEncoderConfig(tt.opts...), zapcore.Entry{Caller: testCaller})
+			assertJSON(t, SourceLocationKey, got[SourceLocationKey], map[string]any{
+				"file":     tt.want,
+				"line":     "42",
+				"function": testCaller.Function,
+			})
+		})
+	}
+}
+
+func TestModuleRelativePath(t *testing.T) {
+	tests := []struct {
+		name        string
+		file        string
+		function    string
+		mainModule  string
+		mainPackage string
+		want        string
+	}{
+		{
+			name:       "module root",
+			file:       "/home/user/src/app/main.go",
+			function:   "example.com/app.Run",
+			mainModule: "example.com/app",
+			want:       "main.go",
+		},
+		{
+			name:       "subpackage",
+			file:       "/home/user/src/app/db/conn.go",
+			function:   "example.com/app/db.(*Conn).Query",
+			mainModule: "example.com/app",
+			want:       "db/conn.go",
+		},
+		{
+			name:        "main package",
+			file:        "/home/user/src/app/cmd/server/main.go",
+			function:    "main.main",
+			mainModule:  "example.com/app",
+			mainPackage: "example.com/app/cmd/server",
+			want:        "cmd/server/main.go",
+		},
+		{
+			name:       "other module",
+			file:       "/home/user/go/pkg/mod/github.com/lib/pq@v1.10.9/conn.go",
+			function:   "github.com/lib/pq.(*conn).query",
+			mainModule: "example.com/app",
+			want:       "github.com/lib/pq/conn.go",
+		},
+		{
+			name:       "module prefix",
+			file:       "/home/user/src/application/conn.go",
+			function:   "example.com/application.Open",
+			mainModule: "example.com/app",
+			want:       "example.com/application/conn.go",
+		},
+		{
+			name:     "no build info",
+			file:     "/home/user/src/app/db/conn.go",
+			function: "example.com/app/db.Open",
+			want:     "example.com/app/db/conn.go",
+		},
+		{
+			name:       "no function",
+			file:       "/home/user/src/app/db/conn.go",
+			mainModule: "example.com/app",
+			want:       "/home/user/src/app/db/conn.go",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			caller := zapcore.EntryCaller{Defined: true, File: tt.file, Function: tt.function}
+			if got := moduleRelativePath(caller, tt.mainModule, tt.mainPackage); got != tt.want {
+				t.Errorf("moduleRelativePath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPackagePath(t *testing.T) {
+	tests := []struct {
+		function string
+		want     string
+	}{
+		{function: "github.com/kechako/zapcloudlogging.(*encoder).Clone", want: "github.com/kechako/zapcloudlogging"},
+		{function: "example.com/app/db.Open.func1", want: "example.com/app/db"},
+		{function: "main.main", want: "main"},
+		// The runtime escapes the dots in the last element of the import path.
+		{function: "gopkg.in/yaml%2ev3.Unmarshal", want: "gopkg.in/yaml.v3"},
+		{function: "", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.function, func(t *testing.T) {
+			if got := packagePath(tt.function); got != tt.want {
+				t.Errorf("packagePath(%q) = %q, want %q", tt.function, got, tt.want)
+			}
+		})
+	}
+}
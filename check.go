@@ -0,0 +1,103 @@
+package zapcloudlogging
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func isStringField(t zapcore.FieldType) bool {
+	return t == zapcore.StringType || t == zapcore.StringerType || t == zapcore.ByteStringType
+}
+
+func isObjectField(t zapcore.FieldType) bool {
+	return t == zapcore.ObjectMarshalerType || t == zapcore.ReflectType
+}
+
+func isTimeField(t zapcore.FieldType) bool {
+	return t == zapcore.TimeType || t == zapcore.TimeFullType || isStringField(t) || isObjectField(t)
+}
+
+// reservedFieldTypes are the types of the fields with the reserved keys expected by Cloud Logging.
+var reservedFieldTypes = map[string]struct {
+	want string
+	ok   func(zapcore.FieldType) bool
+}{
+	SeverityKey:       {"a string", isStringField},
+	MessageKey:        {"a string", isStringField},
+	TimestampKey:      {"a time", isTimeField},
+	LoggerKey:         {"a string", isStringField},
+	StacktraceKey:     {"a string", isStringField},
+	HTTPRequestKey:    {"an object", isObjectField},
+	InsertIDKey:       {"a string", isStringField},
+	LabelsKey:         {"an object", isObjectField},
+	OperationKey:      {"an object", isObjectField},
+	SourceLocationKey: {"an object", isObjectField},
+	SpanIDKey:         {"a string", isStringField},
+	TraceKey:          {"a string", isStringField},
+	TraceSampledKey:   {"a boolean", func(t zapcore.FieldType) bool { return t == zapcore.BoolType }},
+}
+
+// CheckFields returns the errors of the common mistakes in fields about Cloud Logging, e.g. in tests of logging calls:
+//
+//   - A field with a reserved key has a wrong type, e.g. logging.googleapis.com/trace_sampled isn't a boolean.
+//   - A latency, of a field or httpRequest, isn't a google.protobuf.Duration string like "1.5s".
+//   - The line of logging.googleapis.com/sourceLocation isn't a string.
+//
+// It returns nil if no mistakes are found.
+func CheckFields(fields []zap.Field) []error {
+	var errs []error
+	for _, f := range flatten(fields) {
+		if f.Type == zapcore.SkipType {
+			continue
+		}
+		if t, ok := reservedFieldTypes[f.Key]; ok && !t.ok(f.Type) {
+			errs = append(errs, fmt.Errorf("zapcloudlogging: %s must be %s", f.Key, t.want))
+			continue
+		}
+
+		switch f.Key {
+		case "latency":
+			if err := checkLatency(f.Key, fieldValue(f)); err != nil {
+				errs = append(errs, err)
+			}
+		case HTTPRequestKey:
+			if obj, ok := fieldValue(f).(map[string]interface{}); ok {
+				if v, ok := obj["latency"]; ok {
+					if err := checkLatency(HTTPRequestKey+".latency", v); err != nil {
+						errs = append(errs, err)
+					}
+				}
+			}
+		case SourceLocationKey:
+			if obj, ok := fieldValue(f).(map[string]interface{}); ok {
+				if v, ok := obj["line"]; ok {
+					if _, ok := v.(string); !ok {
+						errs = append(errs, fmt.Errorf("zapcloudlogging: %s.line must be a string, got %v", SourceLocationKey, v))
+					}
+				}
+			}
+		}
+	}
+	return errs
+}
+
+// fieldValue returns the value of f encoded by zapcore.MapObjectEncoder.
+func fieldValue(f zapcore.Field) interface{} {
+	enc := zapcore.NewMapObjectEncoder()
+	f.AddTo(enc)
+	return enc.Fields[f.Key]
+}
+
+// checkLatency checks that v is a google.protobuf.Duration string.
+func checkLatency(key string, v interface{}) error {
+	if s, ok := v.(string); ok && strings.HasSuffix(s, "s") {
+		if _, err := strconv.ParseFloat(strings.TrimSuffix(s, "s"), 64); err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf(`zapcloudlogging: %s must be a google.protobuf.Duration string like "1.5s", got %v`, key, v)
+}
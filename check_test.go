@@ -0,0 +1,78 @@
+package zapcloudlogging
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestCheckFields(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields []zap.Field
+		want   []string
+	}{
+		{name: "no fields"},
+		{
+			name: "valid",
+			fields: []zap.Field{
+				zap.String(TraceKey, "projects/my-project/traces/"+testTraceID),
+				zap.Bool(TraceSampledKey, true),
+				zap.String("latency", "1.5s"),
+				HTTPRequestField(&HTTPRequest{Status: 200, Latency: 1500 * time.Millisecond}),
+				zap.Any(SourceLocationKey, map[string]interface{}{"file": "main.go", "line": "42"}),
+				zap.Skip(),
+			},
+		},
+		{
+			name:   "trace_sampled not a boolean",
+			fields: []zap.Field{zap.String(TraceSampledKey, "true")},
+			want:   []string{"zapcloudlogging: logging.googleapis.com/trace_sampled must be a boolean"},
+		},
+		{
+			name:   "labels not an object",
+			fields: []zap.Field{zap.String(LabelsKey, "env=prod")},
+			want:   []string{"zapcloudlogging: logging.googleapis.com/labels must be an object"},
+		},
+		{
+			name:   "trace not a string",
+			fields: []zap.Field{zap.Int(TraceKey, 1)},
+			want:   []string{"zapcloudlogging: logging.googleapis.com/trace must be a string"},
+		},
+		{
+			name:   "latency duration",
+			fields: []zap.Field{zap.Duration("latency", time.Second)},
+			want:   []string{`zapcloudlogging: latency must be a google.protobuf.Duration string like "1.5s", got 1s`},
+		},
+		{
+			name:   "httpRequest latency",
+			fields: []zap.Field{zap.Any(HTTPRequestKey, map[string]interface{}{"latency": 1.5})},
+			want:   []string{`zapcloudlogging: httpRequest.latency must be a google.protobuf.Duration string like "1.5s", got 1.5`},
+		},
+		{
+			name:   "sourceLocation line",
+			fields: []zap.Field{zap.Any(SourceLocationKey, map[string]interface{}{"line": 42})},
+			want:   []string{"zapcloudlogging: logging.googleapis.com/sourceLocation.line must be a string, got 42"},
+		},
+		{
+			name: "grouped",
+			fields: []zap.Field{
+				group(zap.String(TraceSampledKey, "true"), zap.String("latency", "1500ms")),
+			},
+			want: []string{
+				"zapcloudlogging: logging.googleapis.com/trace_sampled must be a boolean",
+				`zapcloudlogging: latency must be a google.protobuf.Duration string like "1.5s", got 1500ms`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, err := range CheckFields(tt.fields) {
+				got = append(got, err.Error())
+			}
+			assertStrings(t, got, tt.want)
+		})
+	}
+}
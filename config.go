@@ -2,7 +2,12 @@
 package zapcloudlogging
 
 import (
+	"fmt"
+	"os"
+	"strconv"
+
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 // NewProductionConfig returns a zapcore.Config for production environments.
@@ -13,6 +18,7 @@ func NewProductionConfig() zap.Config {
 		Sampling: &zap.SamplingConfig{
 			Initial:    100,
 			Thereafter: 100,
+			Hook:       recordSample,
 		},
 		Encoding:         "json",
 		EncoderConfig:    NewProductionEncoderConfig(),
@@ -21,6 +27,48 @@ func NewProductionConfig() zap.Config {
 	}
 }
 
+// NewProductionConfigFromEnv returns a zapcore.Config for production environments,
+// overridden with the environment variables:
+//
+//   - GOOGLE_CLOUD_LOG_MIN_SEVERITY sets the level, see LevelFromEnv.
+//   - LOG_SAMPLING=off disables sampling.
+//   - LOG_SAMPLING_INITIAL and LOG_SAMPLING_THEREAFTER set zap.SamplingConfig.
+//
+// It returns an error if a variable has an invalid value.
+func NewProductionConfigFromEnv() (zap.Config, error) {
+	cfg := NewProductionConfig()
+	cfg.Level = LevelFromEnv()
+
+	switch v := os.Getenv("LOG_SAMPLING"); v {
+	case "":
+	case "off":
+		cfg.Sampling = nil
+		return cfg, nil
+	default:
+		return zap.Config{}, fmt.Errorf("zapcloudlogging: invalid LOG_SAMPLING %q", v)
+	}
+
+	for _, env := range []struct {
+		key string
+		n   *int
+	}{
+		{"LOG_SAMPLING_INITIAL", &cfg.Sampling.Initial},
+		{"LOG_SAMPLING_THEREAFTER", &cfg.Sampling.Thereafter},
+	} {
+		v := os.Getenv(env.key)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return zap.Config{}, fmt.Errorf("zapcloudlogging: invalid %s %q", env.key, v)
+		}
+		*env.n = n
+	}
+
+	return cfg, nil
+}
+
 // NewDevelopmentConfig returns a zapcore.Config for development environments.
 func NewDevelopmentConfig() zap.Config {
 	return zap.Config{
@@ -29,6 +77,7 @@ func NewDevelopmentConfig() zap.Config {
 		Sampling: &zap.SamplingConfig{
 			Initial:    100,
 			Thereafter: 100,
+			Hook:       recordSample,
 		},
 		Encoding:         "json",
 		EncoderConfig:    NewDevelopmentEncoderConfig(),
@@ -36,3 +85,27 @@ func NewDevelopmentConfig() zap.Config {
 		ErrorOutputPaths: []string{"stderr"},
 	}
 }
+
+// NewProduction builds a logger for production environments from NewProductionConfig with opts.
+// The core of the logger is wrapped by WrapCore.
+func NewProduction(opts ...Option) (*zap.Logger, error) {
+	return build(NewProductionConfig(), NewProductionEncoderConfig, opts)
+}
+
+// NewDevelopment builds a logger for development environments from NewDevelopmentConfig with opts.
+// The core of the logger is wrapped by WrapCore.
+func NewDevelopment(opts ...Option) (*zap.Logger, error) {
+	return build(NewDevelopmentConfig(), NewDevelopmentEncoderConfig, opts)
+}
+
+// NewCLILogger returns a logger for short-lived CLI tools, such as batch jobs and Cloud Run jobs.
+// It writes entries at INFO or above synchronously to stderr, without sampling and the caller,
+// so no entries are lost or buffered when the tool exits.
+// Use NewProduction for long-running services.
+func NewCLILogger() *zap.Logger {
+	stderr := zapcore.Lock(os.Stderr)
+	// newEncoder fails only for a message key that can't be marshaled, which the config doesn't have.
+	enc, _ := newEncoder(NewProductionEncoderConfig())
+	core := zapcore.NewCore(enc, stderr, zap.InfoLevel)
+	return zap.New(WrapCore(core), zap.ErrorOutput(stderr))
+}
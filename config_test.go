@@ -0,0 +1,122 @@
+package zapcloudlogging
+
+import (
+	"io"
+	"os"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestNewCLILogger(t *testing.T) {
+	tests := []struct {
+		name        string
+		level       zapcore.Level
+		msg         string
+		wantEntries int
+		wantMessage bool
+	}{
+		{name: "message", level: zapcore.InfoLevel, msg: "done", wantEntries: 1, wantMessage: true},
+		{name: "empty message", level: zapcore.InfoLevel, msg: "", wantEntries: 1, wantMessage: false},
+		{name: "debug", level: zapcore.DebugLevel, msg: "done", wantEntries: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, w, err := os.Pipe()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+			stderr := os.Stderr
+			os.Stderr = w
+			logger := NewCLILogger()
+			os.Stderr = stderr
+
+			logger.Check(tt.level, tt.msg).Write(zap.Int("n", 1))
+			w.Close()
+			out, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			entries := decodeEntries(t, string(out))
+			if len(entries) != tt.wantEntries {
+				t.Fatalf("got %d entries, want %d", len(entries), tt.wantEntries)
+			}
+			if tt.wantEntries == 0 {
+				return
+			}
+			e := entries[0]
+			if msg, ok := e[MessageKey]; ok != tt.wantMessage || ok && msg != tt.msg {
+				t.Errorf("message = %v (%v), want %q (%v)", msg, ok, tt.msg, tt.wantMessage)
+			}
+			if e[SeverityKey] != "INFO" || e["n"] != 1.0 {
+				t.Errorf("entry = %v, want INFO with n", e)
+			}
+			if _, ok := e[SourceLocationKey]; ok {
+				t.Errorf("entry = %v, want no %s", e, SourceLocationKey)
+			}
+		})
+	}
+}
+
+func TestNewProductionConfigFromEnv(t *testing.T) {
+	tests := []struct {
+		name         string
+		env          map[string]string
+		wantLevel    zapcore.Level
+		wantSampling *zap.SamplingConfig
+		wantErr      bool
+	}{
+		{
+			name:         "default",
+			wantLevel:    zapcore.InfoLevel,
+			wantSampling: &zap.SamplingConfig{Initial: 100, Thereafter: 100},
+		},
+		{
+			name:         "level",
+			env:          map[string]string{MinSeverityEnv: "WARNING"},
+			wantLevel:    zapcore.WarnLevel,
+			wantSampling: &zap.SamplingConfig{Initial: 100, Thereafter: 100},
+		},
+		{
+			name:      "sampling off",
+			env:       map[string]string{"LOG_SAMPLING": "off", "LOG_SAMPLING_INITIAL": "invalid"},
+			wantLevel: zapcore.InfoLevel,
+		},
+		{
+			name:         "sampling overridden",
+			env:          map[string]string{"LOG_SAMPLING_INITIAL": "10", "LOG_SAMPLING_THEREAFTER": "0"},
+			wantLevel:    zapcore.InfoLevel,
+			wantSampling: &zap.SamplingConfig{Initial: 10, Thereafter: 0},
+		},
+		{name: "invalid sampling", env: map[string]string{"LOG_SAMPLING": "on"}, wantErr: true},
+		{name: "invalid initial", env: map[string]string{"LOG_SAMPLING_INITIAL": "ten"}, wantErr: true},
+		{name: "negative thereafter", env: map[string]string{"LOG_SAMPLING_THEREAFTER": "-1"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{MinSeverityEnv, "LOG_SAMPLING", "LOG_SAMPLING_INITIAL", "LOG_SAMPLING_THEREAFTER"} {
+				t.Setenv(key, tt.env[key])
+			}
+
+			cfg, err := NewProductionConfigFromEnv()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewProductionConfigFromEnv() error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := cfg.Level.Level(); got != tt.wantLevel {
+				t.Errorf("level = %v, want %v", got, tt.wantLevel)
+			}
+			if (cfg.Sampling == nil) != (tt.wantSampling == nil) {
+				t.Fatalf("sampling = %+v, want %+v", cfg.Sampling, tt.wantSampling)
+			}
+			if s := cfg.Sampling; s != nil && (s.Initial != tt.wantSampling.Initial || s.Thereafter != tt.wantSampling.Thereafter) {
+				t.Errorf("sampling = %+v, want %+v", s, tt.wantSampling)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package zapcloudlogging
+
+import (
+	"context"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestFromContext(t *testing.T) {
+	logger, sink := newTestLogger(t)
+	tests := []struct {
+		name        string
+		ctx         context.Context
+		wantEntries int
+	}{
+		{name: "logger", ctx: NewContext(context.Background(), logger), wantEntries: 1},
+		{name: "no logger", ctx: context.Background()},
+		{name: "nil logger", ctx: NewContext(context.Background(), nil)},
+		{name: "replaced", ctx: NewContext(NewContext(context.Background(), logger), zap.NewNop())},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := len(sink.entries(t))
+			FromContext(tt.ctx).Info("msg")
+			if got := len(sink.entries(t)) - before; got != tt.wantEntries {
+				t.Errorf("got %d entries, want %d", got, tt.wantEntries)
+			}
+		})
+	}
+}
@@ -0,0 +1,429 @@
+package zapcloudlogging
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// exit exits the program, replaced in tests.
+var exit = os.Exit
+
+// WithFatalExitCode returns an Option that makes the logger exit with code
+// after writing an entry at zapcore.FatalLevel (EMERGENCY), instead of 1.
+// It's applied with zap.WithFatalHook, so it replaces the hook set by zap.WithFatalHook or zap.OnFatal, and vice versa.
+func WithFatalExitCode(code int) Option {
+	return WithZapOptions(zap.WithFatalHook(exitHook(code)))
+}
+
+// exitHook is a zapcore.CheckWriteHook that exits the program with the code after the entry is written.
+type exitHook int
+
+func (h exitHook) OnWrite(*zapcore.CheckedEntry, []zapcore.Field) {
+	exit(int(h))
+}
+
+// WithCallerLevel returns an Option that logs the caller as sourceLocation only for entries at level or above,
+// e.g. to skip the cost of capturing the caller for verbose DEBUG entries.
+// By default, the caller is logged for all the entries.
+//
+// The logger is built with the caller disabled, and the caller is captured when an entry at level or above is written,
+// as the first function outside zap and this package, so zap.AddCallerSkip is not applied to these entries.
+func WithCallerLevel(level zapcore.Level) Option {
+	return optionFunc(func(s *settings) {
+		s.config = append(s.config, func(cfg *zap.Config) {
+			cfg.DisableCaller = true
+		})
+		s.logger = append(s.logger, zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return &callerLevelCore{Core: core, level: level}
+		}))
+	})
+}
+
+type callerLevelCore struct {
+	zapcore.Core
+	level zapcore.Level
+}
+
+func (c *callerLevelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &callerLevelCore{
+		Core:  c.Core.With(fields),
+		level: c.level,
+	}
+}
+
+func (c *callerLevelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return checkRewrite(c.Core, ent, ce, c)
+}
+
+func (c *callerLevelCore) rewrite(ent *zapcore.Entry, fields []zapcore.Field) ([]zapcore.Field, bool) {
+	switch {
+	case ent.Level < c.level:
+		ent.Caller = zapcore.EntryCaller{}
+	case !ent.Caller.Defined:
+		ent.Caller = externalCaller()
+	}
+	return fields, true
+}
+
+// packagePrefix is the prefix of the names of the functions of this package.
+var packagePrefix = reflect.TypeOf(callerLevelCore{}).PkgPath() + "."
+
+// maxCallerDepth is the maximum depth of the stack searched by externalCaller.
+const maxCallerDepth = 32
+
+// externalCaller returns the first caller outside zap, this package and the runtime,
+// which is the function that logged the entry being written.
+func externalCaller() zapcore.EntryCaller {
+	var pcs [maxCallerDepth]uintptr
+	// Skip runtime.Callers and externalCaller.
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs[:])])
+	for {
+		f, more := frames.Next()
+		if !isInternalFrame(f) {
+			return zapcore.EntryCaller{
+				Defined:  true,
+				PC:       f.PC,
+				File:     f.File,
+				Line:     f.Line,
+				Function: f.Function,
+			}
+		}
+		if !more {
+			return zapcore.EntryCaller{}
+		}
+	}
+}
+
+// isInternalFrame reports whether f is a frame of zap, this package or the runtime.
+// The tests of this package are not internal.
+func isInternalFrame(f runtime.Frame) bool {
+	switch {
+	case strings.HasPrefix(f.Function, "go.uber.org/zap."), strings.HasPrefix(f.Function, "go.uber.org/zap/"):
+		return true
+	case strings.HasPrefix(f.Function, "runtime."):
+		return true
+	case strings.HasPrefix(f.Function, packagePrefix):
+		return !strings.HasSuffix(f.File, "_test.go")
+	}
+	return false
+}
+
+// WithMaxFields returns an Option that writes at most n fields for every entry, including the fields added by With,
+// and drops the rest, e.g. to keep the entries of runaway code small. The fields with the reserved keys,
+// see IsReservedKey, including labels, are always kept and not counted.
+// When fields are dropped, their number is logged as the fields_truncated field.
+func WithMaxFields(n int) Option {
+	return wrapCore(func(core zapcore.Core) zapcore.Core {
+		return &maxFieldsCore{Core: core, max: n}
+	})
+}
+
+type maxFieldsCore struct {
+	zapcore.Core
+	max int
+	// count is the number of the fields added by With, and dropped the number of those dropped.
+	count   int
+	dropped int
+}
+
+func (c *maxFieldsCore) With(fields []zapcore.Field) zapcore.Core {
+	fields, count, dropped := capFields(flatten(fields), c.max-c.count)
+	return &maxFieldsCore{
+		Core:    c.Core.With(fields),
+		max:     c.max,
+		count:   c.count + count,
+		dropped: c.dropped + dropped,
+	}
+}
+
+func (c *maxFieldsCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return checkRewrite(c.Core, ent, ce, c)
+}
+
+func (c *maxFieldsCore) rewrite(ent *zapcore.Entry, fields []zapcore.Field) ([]zapcore.Field, bool) {
+	fields, _, dropped := capFields(flatten(fields), c.max-c.count)
+	if dropped += c.dropped; dropped > 0 {
+		fields = append(fields[:len(fields):len(fields)], zap.Int("fields_truncated", dropped))
+	}
+	return fields, true
+}
+
+// capFields returns at most n of fields, not counting the fields with the reserved keys and the skipped fields,
+// and the numbers of the counted fields kept and dropped.
+func capFields(fields []zapcore.Field, n int) ([]zapcore.Field, int, int) {
+	kept, dropped := 0, 0
+	var capped []zapcore.Field
+	for i, f := range fields {
+		if f.Type == zapcore.SkipType || IsReservedKey(f.Key) {
+			if capped != nil {
+				capped = append(capped, f)
+			}
+			continue
+		}
+		if kept < n {
+			kept++
+			if capped != nil {
+				capped = append(capped, f)
+			}
+			continue
+		}
+		if capped == nil {
+			capped = append(make([]zapcore.Field, 0, len(fields)-1), fields[:i]...)
+		}
+		dropped++
+	}
+	if capped == nil {
+		return fields, kept, 0
+	}
+	return capped, kept, dropped
+}
+
+// A rewriter rewrites an entry and its fields before they are written.
+// If rewrite returns false, the entry is dropped.
+//
+// It's an interface rather than a function, so that passing a core to checkRewrite doesn't allocate.
+type rewriter interface {
+	rewrite(ent *zapcore.Entry, fields []zapcore.Field) ([]zapcore.Field, bool)
+}
+
+// checkRewrite checks ent with core and, if core will write it, adds a core to ce
+// that rewrites the entry and the fields with r and writes them to core.
+//
+// Unlike adding a wrapping core itself to ce and calling Write of the wrapped core,
+// this keeps the decision of the wrapped core's Check, e.g. of a sampler or a tee.
+func checkRewrite(core zapcore.Core, ent zapcore.Entry, ce *zapcore.CheckedEntry, r rewriter) *zapcore.CheckedEntry {
+	checked := core.Check(ent, nil)
+	if checked == nil {
+		return ce
+	}
+	w := rewriteWriterPool.Get().(*rewriteWriter)
+	w.checked = checked
+	w.rewriter = r
+	return ce.AddCore(ent, w)
+}
+
+// rewriteWriterPool pools rewriteWriters, so that entries without fields don't allocate them.
+var rewriteWriterPool = sync.Pool{
+	New: func() interface{} {
+		return &rewriteWriter{}
+	},
+}
+
+// rewriteWriter is a zapcore.Core added to a zapcore.CheckedEntry by checkRewrite.
+// It is used for a single entry, and returned to rewriteWriterPool when the entry is written.
+type rewriteWriter struct {
+	checked  *zapcore.CheckedEntry
+	rewriter rewriter
+	errOut   errorRecorder
+}
+
+func (w *rewriteWriter) Enabled(zapcore.Level) bool        { return true }
+func (w *rewriteWriter) With([]zapcore.Field) zapcore.Core { return w }
+func (w *rewriteWriter) Sync() error                       { return nil }
+
+func (w *rewriteWriter) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return ce.AddCore(ent, w)
+}
+
+func (w *rewriteWriter) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	defer w.free()
+
+	// The caller and the stack are set by the logger after Check.
+	w.checked.Entry = ent
+	fields, ok := w.rewriter.rewrite(&w.checked.Entry, fields)
+	if !ok {
+		return nil
+	}
+
+	w.checked.ErrorOutput = &w.errOut
+	w.checked.Write(fields...)
+	return w.errOut.err()
+}
+
+func (w *rewriteWriter) free() {
+	*w = rewriteWriter{errOut: errorRecorder{buf: w.errOut.buf[:0]}}
+	rewriteWriterPool.Put(w)
+}
+
+// errorRecorder is a zapcore.WriteSyncer that records the errors reported by
+// zapcore.CheckedEntry.Write.
+type errorRecorder struct {
+	buf []byte
+}
+
+func (r *errorRecorder) Write(p []byte) (int, error) {
+	r.buf = append(r.buf, p...)
+	return len(p), nil
+}
+
+func (r *errorRecorder) Sync() error { return nil }
+
+func (r *errorRecorder) err() error {
+	if len(r.buf) == 0 {
+		return nil
+	}
+	return errors.New(strings.TrimSpace(string(r.buf)))
+}
+
+// WithTraceCore returns a zapcore.Core that adds the fields returned by extract to every entry written to core,
+// e.g. the trace fields of the current request.
+// This is the most general way to attach contextual fields without creating child loggers.
+//
+// extract is called when the entry is written, so it costs nothing for entries dropped by the level or the sampler,
+// but checking an entry allocates an extra zapcore.CheckedEntry, and extract is called for every written entry,
+// so extract should be cheap.
+// Labels returned by extract are merged with the other labels by WrapCore only if core is wrapped by it.
+func WithTraceCore(core zapcore.Core, extract func(zapcore.Entry) []zapcore.Field) zapcore.Core {
+	return &fieldsCore{Core: core, extract: extract}
+}
+
+// fieldsCore is a zapcore.Core that adds the fields returned by extract to every entry.
+type fieldsCore struct {
+	zapcore.Core
+	extract func(zapcore.Entry) []zapcore.Field
+}
+
+func (c *fieldsCore) With(fields []zapcore.Field) zapcore.Core {
+	return &fieldsCore{
+		Core:    c.Core.With(fields),
+		extract: c.extract,
+	}
+}
+
+func (c *fieldsCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return checkRewrite(c.Core, ent, ce, c)
+}
+
+func (c *fieldsCore) rewrite(ent *zapcore.Entry, fields []zapcore.Field) ([]zapcore.Field, bool) {
+	extra := c.extract(*ent)
+	if len(extra) == 0 {
+		return fields, true
+	}
+	return append(extra[:len(extra):len(extra)], fields...), true
+}
+
+// WrapCore wraps core so that the fields of this package are encoded as Cloud Logging expects:
+//
+//   - Labels set by multiple fields, including the fields added by With,
+//     are merged into a single logging.googleapis.com/labels object.
+//     When labels have the same key, the label set later wins.
+//   - A logging.googleapis.com/sourceLocation field, e.g. SourceLocationFromFrame,
+//     replaces the caller of the entry.
+//   - A Severity field overrides the severity of the entry.
+//
+// Loggers built by NewProduction and NewDevelopment are wrapped by WrapCore.
+// For other loggers, it can be passed to zap.WrapCore:
+//
+//	logger, err := zapcloudlogging.NewProductionConfig().Build(zap.WrapCore(zapcloudlogging.WrapCore))
+func WrapCore(core zapcore.Core) zapcore.Core {
+	return &reservedCore{Core: core}
+}
+
+type reservedCore struct {
+	zapcore.Core
+	labels            labels
+	hasSourceLocation bool
+	// defaultLabels are set for the labels missing in the merged labels.
+	defaultLabels labels
+	// sortLabels sorts the merged labels by key.
+	sortLabels bool
+	// severityNumberKey is the key of the severity number, if it's not empty.
+	severityNumberKey string
+	// namespaced are the fields added by With from the first zap.Namespace.
+	// They're added at Write time after the reserved fields, which are kept outside the namespace.
+	namespaced []zapcore.Field
+}
+
+func (c *reservedCore) With(fields []zapcore.Field) zapcore.Core {
+	ls, fields := extractLabels(c.labels, flatten(fields))
+	hasSourceLocation := c.hasSourceLocation || hasField(fields, SourceLocationKey)
+	namespaced := c.namespaced
+	if len(namespaced) > 0 {
+		namespaced = append(namespaced[:len(namespaced):len(namespaced)], fields...)
+		fields = nil
+	} else if i := namespaceIndex(fields); i >= 0 {
+		namespaced = fields[i:]
+		fields = fields[:i]
+	}
+	return &reservedCore{
+		Core:              c.Core.With(fields),
+		labels:            ls,
+		hasSourceLocation: hasSourceLocation,
+		defaultLabels:     c.defaultLabels,
+		sortLabels:        c.sortLabels,
+		severityNumberKey: c.severityNumberKey,
+		namespaced:        namespaced,
+	}
+}
+
+func (c *reservedCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return checkRewrite(c.Core, ent, ce, c)
+}
+
+func (c *reservedCore) rewrite(ent *zapcore.Entry, fields []zapcore.Field) ([]zapcore.Field, bool) {
+	ls, fields := extractLabels(c.labels, flatten(fields))
+	if l, ok := severityOf(fields); ok {
+		ent.Level = l
+	}
+	if c.hasSourceLocation || hasField(fields, SourceLocationKey) {
+		ent.Caller = zapcore.EntryCaller{}
+	}
+	var reserved []zapcore.Field
+	if c.severityNumberKey != "" {
+		n := severityNumber[SeverityOf(ent.Level)]
+		reserved = append(reserved, zap.Int64(c.severityNumberKey, n))
+	}
+	if len(c.defaultLabels) > 0 {
+		ls = c.defaultLabels.merge(ls)
+	}
+	if len(ls) > 0 {
+		if c.sortLabels {
+			ls = ls.sorted()
+		}
+		reserved = append(reserved, zap.Object(LabelsKey, ls))
+	}
+	if len(reserved) == 0 && len(c.namespaced) == 0 {
+		return fields, true
+	}
+
+	// The reserved fields are added before the first namespace, so that they're kept at the top level.
+	i := 0
+	if len(c.namespaced) == 0 {
+		if i = namespaceIndex(fields); i < 0 {
+			i = len(fields)
+		}
+	}
+	out := make([]zapcore.Field, 0, len(fields)+len(reserved)+len(c.namespaced))
+	out = append(out, fields[:i]...)
+	out = append(out, reserved...)
+	out = append(out, c.namespaced...)
+	out = append(out, fields[i:]...)
+	return out, true
+}
+
+// namespaceIndex returns the index of the first zap.Namespace in fields, or -1 if there's none.
+func namespaceIndex(fields []zapcore.Field) int {
+	for i, f := range fields {
+		if f.Type == zapcore.NamespaceType {
+			return i
+		}
+	}
+	return -1
+}
+
+// hasField reports whether fields contain a field with key.
+func hasField(fields []zapcore.Field, key string) bool {
+	for _, f := range fields {
+		if f.Key == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,364 @@
+package zapcloudlogging
+
+import (
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// withCaller is an Option that logs the caller, which is omitted by buildTestLogger.
+var withCaller = WithConfig(func(cfg *zap.Config) {
+	cfg.EncoderConfig.CallerKey = SourceLocationKey
+})
+
+// line returns the line of the caller.
+func line() int {
+	_, _, l, _ := runtime.Caller(1)
+	return l
+}
+
+func TestWithCallerLevel(t *testing.T) {
+	logger, sink := newTestLogger(t, withCaller, WithCallerLevel(zapcore.InfoLevel),
+		WithLevelRef(zap.NewAtomicLevelAt(zapcore.DebugLevel)))
+
+	var wantLines []int
+	tests := []struct {
+		name string
+		log  func()
+		// wantCaller reports whether the entry has the caller.
+		wantCaller bool
+	}{
+		{
+			name: "below level",
+			log:  func() { logger.Debug("debug") },
+		},
+		{
+			name:       "at level",
+			log:        func() { wantLines = append(wantLines, line()); logger.Info("info") },
+			wantCaller: true,
+		},
+		{
+			name:       "above level with fields",
+			log:        func() { wantLines = append(wantLines, line()); logger.With(zap.Int("n", 1)).Warn("warn") },
+			wantCaller: true,
+		},
+		{
+			name:       "sugared",
+			log:        func() { wantLines = append(wantLines, line()); logger.Sugar().Errorw("error") },
+			wantCaller: true,
+		},
+		{
+			name:       "deferred by LogSpan",
+			log:        func() { wantLines = append(wantLines, line()); LogSpan(logger, "span")() },
+			wantCaller: true,
+		},
+	}
+	for _, tt := range tests {
+		tt.log()
+	}
+
+	entries := sink.entries(t)
+	if len(entries) != len(tests) {
+		t.Fatalf("got %d entries, want %d", len(entries), len(tests))
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc, ok := entries[i][SourceLocationKey].(map[string]any)
+			if ok != tt.wantCaller {
+				t.Fatalf("sourceLocation = %v, want it: %v", entries[i][SourceLocationKey], tt.wantCaller)
+			}
+			if !ok {
+				return
+			}
+			if file, _ := loc["file"].(string); !strings.HasSuffix(file, "core_test.go") {
+				t.Errorf("sourceLocation.file = %s, want core_test.go", file)
+			}
+			if !strings.HasPrefix(loc["function"].(string), packagePrefix+"TestWithCallerLevel") {
+				t.Errorf("sourceLocation.function = %s, want TestWithCallerLevel", loc["function"])
+			}
+		})
+	}
+
+	var i int
+	for _, e := range entries {
+		if loc, ok := e[SourceLocationKey].(map[string]any); ok {
+			if want := strconv.Itoa(wantLines[i]); loc["line"] != want {
+				t.Errorf("entry %d: sourceLocation.line = %v, want %s", i, loc["line"], want)
+			}
+			i++
+		}
+	}
+}
+
+func BenchmarkCallerLevel(b *testing.B) {
+	benchmarks := []struct {
+		name string
+		opts []Option
+	}{
+		{name: "default", opts: []Option{withCaller}},
+		{name: "WithCallerLevel", opts: []Option{withCaller, WithCallerLevel(zapcore.InfoLevel)}},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			logger, sink := buildTestLogger(b, NewDevelopmentConfig(), NewDevelopmentEncoderConfig, bm.opts...)
+			sink.discard = true
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				logger.Debug("debug")
+			}
+		})
+	}
+}
+
+func TestWithFatalExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		code int
+	}{
+		{name: "zero", code: 0},
+		{name: "non-zero", code: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, sink := newTestLogger(t, WithFatalExitCode(tt.code))
+
+			codes := []int{}
+			var entries int
+			exit = func(code int) {
+				codes = append(codes, code)
+				entries = len(sink.entries(t))
+			}
+			defer func() { exit = os.Exit }()
+
+			logger.Error("error")
+			logger.Fatal("fatal")
+
+			if len(codes) != 1 || codes[0] != tt.code {
+				t.Errorf("exited with %v, want [%d]", codes, tt.code)
+			}
+			if entries != 2 {
+				t.Errorf("%d entries were written before exiting, want 2", entries)
+			}
+		})
+	}
+}
+
+// withoutSampling is an Option that disables sampling, so that benchmarks write every entry.
+var withoutSampling = WithConfig(func(cfg *zap.Config) {
+	cfg.Sampling = nil
+})
+
+// BenchmarkNoFields compares the cost of an entry without fields of the loggers built by this package
+// with that of the logger built by zap from the same config.
+func BenchmarkNoFields(b *testing.B) {
+	raw := func(b *testing.B) *zap.Logger {
+		path, sink := newTestSinkPath(b)
+		sink.discard = true
+		cfg := NewProductionConfig()
+		cfg.OutputPaths = []string{path}
+		cfg.EncoderConfig.TimeKey = zapcore.OmitKey
+		cfg.EncoderConfig.CallerKey = zapcore.OmitKey
+		cfg.Sampling = nil
+		logger, err := cfg.Build()
+		if err != nil {
+			b.Fatal(err)
+		}
+		return logger
+	}
+	built := func(opts ...Option) func(*testing.B) *zap.Logger {
+		return func(b *testing.B) *zap.Logger {
+			logger, sink := newTestLogger(b, append([]Option{withoutSampling}, opts...)...)
+			sink.discard = true
+			return logger
+		}
+	}
+	benchmarks := []struct {
+		name   string
+		logger func(*testing.B) *zap.Logger
+	}{
+		{name: "zap.Config", logger: raw},
+		{name: "NewProduction", logger: built()},
+		{name: "NewProduction with wrapping options", logger: built(WithUptime(), WithComponentLabel(), WithMaxFields(10))},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			logger := bm.logger(b)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				logger.Info("msg")
+			}
+		})
+	}
+}
+
+func TestWithTraceCore(t *testing.T) {
+	tests := []struct {
+		name    string
+		extract func(zapcore.Entry) []zapcore.Field
+		with    []zap.Field
+		want    map[string]any
+	}{
+		{
+			name:    "none",
+			extract: func(zapcore.Entry) []zapcore.Field { return nil },
+			want:    map[string]any{SeverityKey: "INFO", MessageKey: "msg", "n": 1},
+		},
+		{
+			name: "fields",
+			extract: func(zapcore.Entry) []zapcore.Field {
+				return []zapcore.Field{zap.String(TraceKey, "projects/p/traces/t"), Label("env", "prod")}
+			},
+			with: []zap.Field{Label("region", "asia")},
+			want: map[string]any{
+				SeverityKey: "INFO",
+				MessageKey:  "msg",
+				TraceKey:    "projects/p/traces/t",
+				LabelsKey:   map[string]any{"env": "prod", "region": "asia"},
+				"n":         1,
+			},
+		},
+		{
+			name: "entry",
+			extract: func(ent zapcore.Entry) []zapcore.Field {
+				return []zapcore.Field{zap.String("logged_message", ent.Message)}
+			},
+			with: []zap.Field{zap.String("component", "db")},
+			want: map[string]any{
+				SeverityKey:      "INFO",
+				MessageKey:       "msg",
+				"component":      "db",
+				"logged_message": "msg",
+				"n":              1,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, sink := newTestCore(t)
+			logger := zap.New(WithTraceCore(WrapCore(core), tt.extract)).With(tt.with...)
+			logger.Info("msg", zap.Int("n", 1))
+
+			assertJSON(t, "entries", sink.entries(t), []map[string]any{tt.want})
+		})
+	}
+}
+
+func TestWithMaxFields(t *testing.T) {
+	tests := []struct {
+		name   string
+		max    int
+		with   []zap.Field
+		fields []zap.Field
+		want   map[string]any
+	}{
+		{
+			name:   "under",
+			max:    3,
+			fields: []zap.Field{zap.Int("a", 1), zap.Int("b", 2)},
+			want:   map[string]any{SeverityKey: "INFO", MessageKey: "msg", "a": 1, "b": 2},
+		},
+		{
+			name:   "over",
+			max:    2,
+			fields: []zap.Field{zap.Int("a", 1), zap.Int("b", 2), zap.Int("c", 3), zap.Int("d", 4)},
+			want:   map[string]any{SeverityKey: "INFO", MessageKey: "msg", "a": 1, "b": 2, "fields_truncated": 2},
+		},
+		{
+			name:   "with counted",
+			max:    2,
+			with:   []zap.Field{zap.Int("a", 1)},
+			fields: []zap.Field{zap.Int("b", 2), zap.Int("c", 3)},
+			want:   map[string]any{SeverityKey: "INFO", MessageKey: "msg", "a": 1, "b": 2, "fields_truncated": 1},
+		},
+		{
+			name:   "with dropped",
+			max:    1,
+			with:   []zap.Field{zap.Int("a", 1), zap.Int("b", 2)},
+			fields: []zap.Field{zap.Int("c", 3)},
+			want:   map[string]any{SeverityKey: "INFO", MessageKey: "msg", "a": 1, "fields_truncated": 2},
+		},
+		{
+			name:   "reserved kept",
+			max:    1,
+			fields: []zap.Field{Label("env", "prod"), zap.Int("a", 1), Severity(zapcore.WarnLevel), zap.Int("b", 2)},
+			want: map[string]any{
+				SeverityKey: "WARNING", MessageKey: "msg", LabelsKey: map[string]string{"env": "prod"},
+				"a": 1, "fields_truncated": 1,
+			},
+		},
+		{
+			name:   "grouped",
+			max:    1,
+			fields: []zap.Field{group(zap.Int("a", 1), zap.Int("b", 2))},
+			want:   map[string]any{SeverityKey: "INFO", MessageKey: "msg", "a": 1, "fields_truncated": 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, sink := newTestLogger(t, WithMaxFields(tt.max))
+			logger.With(tt.with...).Info("msg", tt.fields...)
+
+			assertJSON(t, "entry", sink.entries(t)[0], tt.want)
+		})
+	}
+}
+
+func TestWrapCoreNamespace(t *testing.T) {
+	tests := []struct {
+		name   string
+		with   []zap.Field
+		fields []zap.Field
+		want   map[string]any
+	}{
+		{
+			name:   "entry",
+			fields: []zap.Field{zap.Int("a", 1), zap.Namespace("req"), Label("k", "v"), zap.Int("b", 2)},
+			want: map[string]any{
+				SeverityKey: "INFO", MessageKey: "msg", "severity_number": 200, LabelsKey: map[string]string{"k": "v"},
+				"a": 1, "req": map[string]any{"b": 2},
+			},
+		},
+		{
+			name:   "with",
+			with:   []zap.Field{zap.Int("a", 1), zap.Namespace("req"), zap.Int("b", 2)},
+			fields: []zap.Field{Label("k", "v"), zap.Int("c", 3)},
+			want: map[string]any{
+				SeverityKey: "INFO", MessageKey: "msg", "severity_number": 200, LabelsKey: map[string]string{"k": "v"},
+				"a": 1, "req": map[string]any{"b": 2, "c": 3},
+			},
+		},
+		{
+			name:   "nested",
+			with:   []zap.Field{zap.Namespace("req"), Label("k", "v")},
+			fields: []zap.Field{zap.Namespace("db"), zap.Int("a", 1)},
+			want: map[string]any{
+				SeverityKey: "INFO", MessageKey: "msg", "severity_number": 200, LabelsKey: map[string]string{"k": "v"},
+				"req": map[string]any{"db": map[string]any{"a": 1}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, sink := newTestLogger(t, WithSeverityNumberField("severity_number"))
+			logger.With(tt.with...).Info("msg", tt.fields...)
+
+			assertJSON(t, "entry", sink.entries(t)[0], tt.want)
+		})
+	}
+
+	t.Run("with twice", func(t *testing.T) {
+		logger, sink := newTestLogger(t)
+		logger.With(zap.Namespace("req")).With(zap.Int("a", 1), Label("k", "v")).Info("msg")
+
+		assertJSON(t, "entry", sink.entries(t)[0], map[string]any{
+			SeverityKey: "INFO", MessageKey: "msg", LabelsKey: map[string]string{"k": "v"}, "req": map[string]any{"a": 1},
+		})
+	})
+}
@@ -0,0 +1,71 @@
+package zapcloudlogging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"time"
+)
+
+// CorrelationIDHeader is the HTTP header of the correlation ID read by Middleware
+// and forwarded by the transport returned by NewLoggingTransport.
+const CorrelationIDHeader = "X-Correlation-ID"
+
+// maxCorrelationIDLen is the maximum length of a correlation ID read from a header.
+const maxCorrelationIDLen = 128
+
+type correlationIDKey struct{}
+
+// withCorrelationID returns a copy of ctx that carries id.
+func withCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationIDKey{}, id)
+}
+
+// correlationIDFrom returns the correlation ID carried by ctx.
+func correlationIDFrom(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(correlationIDKey{}).(string)
+	return id, ok && id != ""
+}
+
+// EnsureCorrelationID returns a copy of ctx that carries a correlation ID, and the ID,
+// so that the entries and the outgoing requests of an operation share it even without a trace.
+// The ID is the one ctx already carries, the trace ID of the trace context stored by Middleware,
+// or a new ULID, in this order.
+//
+// https://github.com/ulid/spec
+func EnsureCorrelationID(ctx context.Context) (context.Context, string) {
+	if id, ok := correlationIDFrom(ctx); ok {
+		return ctx, id
+	}
+	id := ""
+	if tc, ok := traceContextFrom(ctx); ok {
+		id = tc.traceID
+	} else {
+		id = newULID(time.Now())
+	}
+	return withCorrelationID(ctx, id), id
+}
+
+// crockford is the Base32 alphabet of Douglas Crockford used by ULIDs.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// newULID returns a ULID of t: the milliseconds since the Unix epoch in 48 bits
+// followed by 80 random bits, encoded in 26 characters of Crockford's Base32.
+func newULID(t time.Time) string {
+	var b [16]byte
+	ms := uint64(t.UnixMilli())
+	binary.BigEndian.PutUint16(b[0:2], uint16(ms>>32))
+	binary.BigEndian.PutUint32(b[2:6], uint32(ms))
+	// The random bits are best effort, as an ID for logs.
+	_, _ = rand.Read(b[6:])
+
+	// 128 bits are encoded in 26 characters of 5 bits, from the most significant bits with 2 bits of padding.
+	hi, lo := binary.BigEndian.Uint64(b[0:8]), binary.BigEndian.Uint64(b[8:16])
+	var s [26]byte
+	for i := len(s) - 1; i >= 0; i-- {
+		s[i] = crockford[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(s[:])
+}
@@ -0,0 +1,163 @@
+package zapcloudlogging
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ulidPattern matches the ULIDs returned by newULID.
+var ulidPattern = regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Z]{25}$`)
+
+func TestEnsureCorrelationID(t *testing.T) {
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want string
+	}{
+		{name: "carried", ctx: withCorrelationID(context.Background(), "corr-1"), want: "corr-1"},
+		{
+			name: "carried over trace",
+			ctx:  withCorrelationID(withTraceContext(context.Background(), traceContext{traceID: testTraceID}), "corr-1"),
+			want: "corr-1",
+		},
+		{name: "trace", ctx: withTraceContext(context.Background(), traceContext{traceID: testTraceID}), want: testTraceID},
+		{name: "empty ID", ctx: withCorrelationID(context.Background(), ""), want: ""},
+		{name: "none", ctx: context.Background(), want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, id := EnsureCorrelationID(tt.ctx)
+			if tt.want == "" {
+				if !ulidPattern.MatchString(id) {
+					t.Errorf("EnsureCorrelationID() = %q, want a ULID", id)
+				}
+			} else if id != tt.want {
+				t.Errorf("EnsureCorrelationID() = %q, want %q", id, tt.want)
+			}
+
+			if got, _ := correlationIDFrom(ctx); got != id {
+				t.Errorf("context carries %q, want %q", got, id)
+			}
+			if _, again := EnsureCorrelationID(ctx); again != id {
+				t.Errorf("EnsureCorrelationID() again = %q, want %q", again, id)
+			}
+		})
+	}
+}
+
+func TestNewULID(t *testing.T) {
+	tests := []struct {
+		name string
+		t    time.Time
+		want string
+	}{
+		{name: "epoch", t: time.UnixMilli(0), want: "0000000000"},
+		// The example of the ULID spec.
+		{name: "spec", t: time.UnixMilli(1469918176385), want: "01ARYZ6S41"},
+		{name: "max", t: time.UnixMilli(1<<48 - 1), want: "7ZZZZZZZZZ"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := newULID(tt.t), newULID(tt.t)
+			if !ulidPattern.MatchString(a) {
+				t.Fatalf("newULID() = %q, want a ULID", a)
+			}
+			if !strings.HasPrefix(a, tt.want) {
+				t.Errorf("newULID() = %q, want the time %s", a, tt.want)
+			}
+			if a == b {
+				t.Errorf("newULID() = %q twice, want random bits", a)
+			}
+		})
+	}
+}
+
+func TestMiddlewareCorrelationID(t *testing.T) {
+	tests := []struct {
+		name   string
+		header http.Header
+		want   string
+	}{
+		{name: "header", header: http.Header{CorrelationIDHeader: {"corr-1"}}, want: "corr-1"},
+		{
+			name:   "header over trace",
+			header: http.Header{CorrelationIDHeader: {"corr-1"}, CloudTraceContextHeader: {testTraceID + "/74;o=1"}},
+			want:   "corr-1",
+		},
+		{name: "trace", header: http.Header{CloudTraceContextHeader: {testTraceID + "/74;o=1"}}, want: testTraceID},
+		{name: "too long", header: http.Header{CorrelationIDHeader: {strings.Repeat("a", maxCorrelationIDLen+1)}}},
+		{name: "none"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			labels, _ := serveMiddleware(t, tt.header)[LabelsKey].(map[string]any)
+			id, _ := labels["correlation_id"].(string)
+			if tt.want == "" {
+				if !ulidPattern.MatchString(id) {
+					t.Errorf("correlation_id = %q, want a ULID", id)
+				}
+				return
+			}
+			if id != tt.want {
+				t.Errorf("correlation_id = %q, want %q", id, tt.want)
+			}
+		})
+	}
+}
+
+func TestCorrelationIDPropagation(t *testing.T) {
+	tests := []struct {
+		name   string
+		header http.Header
+		want   string
+	}{
+		{name: "forwarded", header: http.Header{CorrelationIDHeader: {"corr-1"}}, want: "corr-1"},
+		{name: "ensured", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.Header.Get(CorrelationIDHeader)
+			}))
+			defer backend.Close()
+
+			logger, _ := newTestLogger(t)
+			client := &http.Client{Transport: NewLoggingTransport(nil, zap.NewNop())}
+			var id string
+			handler := Middleware(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var ctx context.Context
+				ctx, id = EnsureCorrelationID(r.Context())
+				req, err := http.NewRequestWithContext(ctx, http.MethodGet, backend.URL, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				res, err := client.Do(req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				res.Body.Close()
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			for k, vs := range tt.header {
+				r.Header[http.CanonicalHeaderKey(k)] = vs
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), r)
+
+			if tt.want != "" && id != tt.want {
+				t.Errorf("EnsureCorrelationID() = %q, want %q", id, tt.want)
+			}
+			if got != id {
+				t.Errorf("%s forwarded = %q, want %q", CorrelationIDHeader, got, id)
+			}
+		})
+	}
+}
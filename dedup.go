@@ -0,0 +1,142 @@
+package zapcloudlogging
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// NewDedupCore returns a zapcore.Core that collapses the entries with the same level and message
+// written to it within window into a single entry, e.g. of a noisy retry loop.
+// Unlike the sampler of zap, which drops entries, the entry is written when the window of its first occurrence closes,
+// with the fields of the first occurrence and the number of the occurrences as the repeat_count field.
+// Entries at DPANIC or above are written immediately, since the program may crash.
+//
+// The entries are delayed by up to window; Sync writes the pending entries,
+// so sync the logger before the program exits.
+func NewDedupCore(core zapcore.Core, window time.Duration) zapcore.Core {
+	return &dedupCore{
+		Core: core,
+		state: &dedupState{
+			window:  window,
+			pending: make(map[dedupKey]*dedupEntry),
+		},
+	}
+}
+
+type dedupCore struct {
+	zapcore.Core
+	state *dedupState
+}
+
+func (c *dedupCore) With(fields []zapcore.Field) zapcore.Core {
+	return &dedupCore{
+		Core:  c.Core.With(fields),
+		state: c.state,
+	}
+}
+
+func (c *dedupCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *dedupCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if ent.Level >= zapcore.DPanicLevel {
+		return c.Core.Write(ent, fields)
+	}
+	c.state.add(c.Core, ent, fields)
+	return nil
+}
+
+func (c *dedupCore) Sync() error {
+	return multierr.Append(c.state.flushAll(), c.Core.Sync())
+}
+
+type dedupKey struct {
+	level   zapcore.Level
+	message string
+}
+
+// dedupEntry is the first occurrence of an entry in a window, and the number of the occurrences.
+type dedupEntry struct {
+	core   zapcore.Core
+	ent    zapcore.Entry
+	fields []zapcore.Field
+	n      int
+	timer  *time.Timer
+}
+
+func (e *dedupEntry) write() error {
+	fields := e.fields
+	if e.n > 1 {
+		fields = append(fields[:len(fields):len(fields)], zap.Int("repeat_count", e.n))
+	}
+	return e.core.Write(e.ent, fields)
+}
+
+// dedupState is the pending entries shared by a dedupCore and its children.
+type dedupState struct {
+	window time.Duration
+
+	mu      sync.Mutex
+	pending map[dedupKey]*dedupEntry
+}
+
+func (s *dedupState) add(core zapcore.Core, ent zapcore.Entry, fields []zapcore.Field) {
+	key := dedupKey{level: ent.Level, message: ent.Message}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.pending[key]; ok {
+		e.n++
+		return
+	}
+	e := &dedupEntry{
+		core: core,
+		ent:  ent,
+		// The fields may be reused by the caller after Write returns.
+		fields: append([]zapcore.Field(nil), fields...),
+		n:      1,
+	}
+	e.timer = time.AfterFunc(s.window, func() { s.flush(key, e) })
+	s.pending[key] = e
+}
+
+// flush writes the pending entry e of key when its window closes.
+// It's not written if it's already written by flushAll, which may have been followed by a new entry of key.
+func (s *dedupState) flush(key dedupKey, e *dedupEntry) {
+	s.mu.Lock()
+	ok := s.pending[key] == e
+	if ok {
+		delete(s.pending, key)
+	}
+	s.mu.Unlock()
+
+	if ok {
+		// There is no caller to return the error to, as with the sampler of zap.
+		_ = e.write()
+	}
+}
+
+// flushAll writes all the pending entries.
+func (s *dedupState) flushAll() error {
+	s.mu.Lock()
+	pending := s.pending
+	s.pending = make(map[dedupKey]*dedupEntry)
+	for _, e := range pending {
+		e.timer.Stop()
+	}
+	s.mu.Unlock()
+
+	var err error
+	for _, e := range pending {
+		err = multierr.Append(err, e.write())
+	}
+	return err
+}
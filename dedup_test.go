@@ -0,0 +1,164 @@
+package zapcloudlogging
+
+import (
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestNewDedupCore(t *testing.T) {
+	tests := []struct {
+		name string
+		log  func(logger *zap.Logger)
+		// wantImmediate is the number of the entries written before Sync.
+		wantImmediate int
+		want          []map[string]any
+	}{
+		{
+			name: "once",
+			log:  func(logger *zap.Logger) { logger.Info("retrying", zap.Int("attempt", 1)) },
+			want: []map[string]any{{SeverityKey: "INFO", MessageKey: "retrying", "attempt": 1}},
+		},
+		{
+			name: "repeated",
+			log: func(logger *zap.Logger) {
+				for i := 1; i <= 3; i++ {
+					logger.Info("retrying", zap.Int("attempt", i))
+				}
+			},
+			want: []map[string]any{{SeverityKey: "INFO", MessageKey: "retrying", "attempt": 1, "repeat_count": 3}},
+		},
+		{
+			name: "hundred",
+			log: func(logger *zap.Logger) {
+				for i := 0; i < 100; i++ {
+					logger.Info("retrying")
+				}
+			},
+			want: []map[string]any{{SeverityKey: "INFO", MessageKey: "retrying", "repeat_count": 100}},
+		},
+		{
+			name: "levels",
+			log: func(logger *zap.Logger) {
+				logger.Info("retrying")
+				logger.Warn("retrying")
+				logger.Warn("retrying")
+			},
+			want: []map[string]any{
+				{SeverityKey: "INFO", MessageKey: "retrying"},
+				{SeverityKey: "WARNING", MessageKey: "retrying", "repeat_count": 2},
+			},
+		},
+		{
+			name: "with",
+			log: func(logger *zap.Logger) {
+				logger.With(zap.String("queue", "a")).Info("retrying")
+				logger.With(zap.String("queue", "b")).Info("retrying")
+			},
+			want: []map[string]any{{SeverityKey: "INFO", MessageKey: "retrying", "queue": "a", "repeat_count": 2}},
+		},
+		{
+			name: "dpanic",
+			log: func(logger *zap.Logger) {
+				logger.DPanic("corrupted")
+				logger.DPanic("corrupted")
+			},
+			wantImmediate: 2,
+			want: []map[string]any{
+				{SeverityKey: "CRITICAL", MessageKey: "corrupted"},
+				{SeverityKey: "CRITICAL", MessageKey: "corrupted"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, sink := newTestCore(t)
+			logger := zap.New(NewDedupCore(core, time.Hour))
+			tt.log(logger)
+
+			if n := len(sink.entries(t)); n != tt.wantImmediate {
+				t.Errorf("got %d entries before Sync, want %d", n, tt.wantImmediate)
+			}
+			if err := logger.Sync(); err != nil {
+				t.Fatalf("Sync() = %v", err)
+			}
+			got := sink.entries(t)
+			// The pending entries are written in no particular order.
+			sort.SliceStable(got, func(i, j int) bool {
+				return got[i][SeverityKey].(string) < got[j][SeverityKey].(string)
+			})
+			assertJSON(t, "entries", got, tt.want)
+		})
+	}
+
+	t.Run("window", func(t *testing.T) {
+		core, sink := newTestCore(t)
+		logger := zap.New(NewDedupCore(core, 10*time.Millisecond))
+		logger.Info("retrying")
+		logger.Info("retrying")
+
+		deadline := time.Now().Add(5 * time.Second)
+		for len(sink.entries(t)) == 0 && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		assertJSON(t, "entries", sink.entries(t), []map[string]any{
+			{SeverityKey: "INFO", MessageKey: "retrying", "repeat_count": 2},
+		})
+
+		logger.Info("retrying")
+		if err := logger.Sync(); err != nil {
+			t.Fatalf("Sync() = %v", err)
+		}
+		if n := len(sink.entries(t)); n != 2 {
+			t.Errorf("got %d entries, want a new window after it closed", n)
+		}
+	})
+	t.Run("sync while the window closes", func(t *testing.T) {
+		const n = 1000
+		core, sink := newTestCore(t)
+		logger := zap.New(NewDedupCore(core, time.Microsecond))
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < n; i++ {
+				logger.Info("retrying")
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := 0; i < n; i++ {
+				_ = logger.Sync()
+			}
+		}()
+		wg.Wait()
+		if err := logger.Sync(); err != nil {
+			t.Fatalf("Sync() = %v", err)
+		}
+
+		// Every occurrence is counted once, by an entry written by Sync or when its window closed.
+		// The last window may still be written by its timer after Sync returns.
+		count := func() int {
+			total := 0
+			for _, e := range sink.entries(t) {
+				if c, ok := e["repeat_count"].(float64); ok {
+					total += int(c)
+				} else {
+					total++
+				}
+			}
+			return total
+		}
+		deadline := time.Now().Add(5 * time.Second)
+		for count() < n && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		if got := count(); got != n {
+			t.Errorf("counted %d occurrences, want %d", got, n)
+		}
+	})
+}
@@ -0,0 +1,33 @@
+package zapcloudlogging
+
+import (
+	"sync/atomic"
+
+	"go.uber.org/zap"
+)
+
+// defaultLogger holds the *zap.Logger returned by L.
+var defaultLogger atomic.Value
+
+func init() {
+	defaultLogger.Store(zap.NewNop())
+}
+
+// SetDefault replaces the logger returned by L with l, typically a logger built by NewProduction at startup.
+// If l is nil, L returns a no-op logger again.
+// Unlike zap.ReplaceGlobals, it doesn't replace the global loggers of zap nor return a function restoring them.
+// It's safe to call concurrently with L.
+func SetDefault(l *zap.Logger) {
+	if l == nil {
+		l = zap.NewNop()
+	}
+	defaultLogger.Store(l)
+}
+
+// L returns the default logger set by SetDefault, e.g. for libraries logging deep in a call stack
+// where passing a logger or a context is impractical. Until SetDefault is called, it's a no-op logger,
+// so that nothing is logged before the logger is configured.
+// Prefer FromContext for the loggers of requests.
+func L() *zap.Logger {
+	return defaultLogger.Load().(*zap.Logger)
+}
@@ -0,0 +1,69 @@
+package zapcloudlogging
+
+import (
+	"sync"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestSetDefault(t *testing.T) {
+	t.Cleanup(func() { SetDefault(nil) })
+
+	tests := []struct {
+		name string
+		set  bool
+		want int
+	}{
+		{name: "unset", want: 0},
+		{name: "set", set: true, want: 1},
+		{name: "reset with nil", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, sink := newTestLogger(t)
+			if tt.set {
+				SetDefault(logger)
+			} else {
+				SetDefault(nil)
+			}
+			L().Info("msg")
+
+			if n := len(sink.entries(t)); n != tt.want {
+				t.Errorf("got %d entries, want %d", n, tt.want)
+			}
+			if L() == nil {
+				t.Error("L() = nil, want a logger")
+			}
+		})
+	}
+
+	t.Run("globals untouched", func(t *testing.T) {
+		global := zap.L()
+		logger, _ := newTestLogger(t)
+		SetDefault(logger)
+		if zap.L() != global {
+			t.Error("SetDefault replaced the global logger of zap")
+		}
+	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		logger, sink := newTestLogger(t)
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				SetDefault(logger)
+			}()
+			go func() {
+				defer wg.Done()
+				L().Info("msg")
+			}()
+		}
+		wg.Wait()
+		if n := len(sink.entries(t)); n > 8 {
+			t.Errorf("got %d entries, want at most 8", n)
+		}
+	})
+}
@@ -0,0 +1,51 @@
+package zapcloudlogging
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// formatDuration formats d in the JSON representation of google.protobuf.Duration,
+// e.g. "1.5s", with 0, 3, 6 or 9 fractional digits.
+//
+// https://protobuf.dev/reference/protobuf/google.protobuf/#duration
+func formatDuration(d time.Duration) string {
+	var sign string
+	u := uint64(d)
+	if d < 0 {
+		sign = "-"
+		u = -u
+	}
+	sec, nanos := u/uint64(time.Second), u%uint64(time.Second)
+
+	var frac string
+	switch {
+	case nanos == 0:
+	case nanos%1e6 == 0:
+		frac = fmt.Sprintf(".%03d", nanos/1e6)
+	case nanos%1e3 == 0:
+		frac = fmt.Sprintf(".%06d", nanos/1e3)
+	default:
+		frac = fmt.Sprintf(".%09d", nanos)
+	}
+	return sign + strconv.FormatUint(sec, 10) + frac + "s"
+}
+
+// DurationProto returns a zap.Field that logs d as a google.protobuf.Duration, e.g. "1.234s",
+// regardless of the duration encoder of the encoder config.
+func DurationProto(key string, d time.Duration) zap.Field {
+	return zap.String(key, formatDuration(d))
+}
+
+// FormatLatency formats d as the latency of httpRequest, a google.protobuf.Duration like "1.5s",
+// with 0, 3, 6 or 9 fractional digits, e.g. "1s" or "0.001500s".
+// A negative latency, e.g. measured with a wall clock that moved backwards, is formatted as "0s".
+func FormatLatency(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	return formatDuration(d)
+}
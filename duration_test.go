@@ -0,0 +1,57 @@
+package zapcloudlogging
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestDurationProto(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{d: 0, want: "0s"},
+		{d: time.Second, want: "1s"},
+		{d: 1500 * time.Millisecond, want: "1.500s"},
+		{d: 1500 * time.Microsecond, want: "0.001500s"},
+		{d: 1500 * time.Nanosecond, want: "0.000001500s"},
+		{d: -1500 * time.Millisecond, want: "-1.500s"},
+		{d: 90 * time.Minute, want: "5400s"},
+		{d: math.MinInt64, want: "-9223372036.854775808s"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			got := encodeFields(t, DurationProto("elapsed", tt.d))
+			assertJSON(t, "elapsed", got["elapsed"], tt.want)
+		})
+	}
+}
+
+func TestFormatLatency(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{d: 0, want: "0s"},
+		{d: time.Second, want: "1s"},
+		{d: 1500 * time.Millisecond, want: "1.500s"},
+		{d: 1500 * time.Microsecond, want: "0.001500s"},
+		{d: time.Nanosecond, want: "0.000000001s"},
+		{d: -time.Millisecond, want: "0s"},
+		{d: math.MinInt64, want: "0s"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.d.String(), func(t *testing.T) {
+			got := encodeFields(t, HTTPRequestField(&HTTPRequest{Status: 200, Latency: tt.d}))
+			if tt.d == 0 {
+				// A zero latency is omitted from httpRequest.
+				if got := FormatLatency(tt.d); got != tt.want {
+					t.Errorf("FormatLatency(%v) = %q, want %q", tt.d, got, tt.want)
+				}
+				return
+			}
+			assertJSON(t, "httpRequest", got[HTTPRequestKey], map[string]any{"status": 200, "latency": tt.want})
+		})
+	}
+}
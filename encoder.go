@@ -1,9 +1,17 @@
 package zapcloudlogging
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
 	"strconv"
 	"time"
 
+	"go.uber.org/zap"
+	"go.uber.org/zap/buffer"
 	"go.uber.org/zap/zapcore"
 )
 
@@ -21,7 +29,7 @@ var logLevelSeverity = map[zapcore.Level]string{
 //
 // https://cloud.google.com/logging/docs/reference/v2/rest/v2/LogEntry#logseverity
 func severityEncoder(l zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
-	enc.AppendString(logLevelSeverity[l])
+	enc.AppendString(SeverityOf(l))
 }
 
 type sourceLocation struct {
@@ -37,18 +45,36 @@ func (l sourceLocation) MarshalLogObject(enc zapcore.ObjectEncoder) error {
 	return nil
 }
 
-// sourceLocationEncoder is a encoder for SourceLocation.
+// SourceLocationFromFrame returns a zap.Field that sets logging.googleapis.com/sourceLocation to f,
+// e.g. a frame of a captured call stack.
+// The caller of the entry, which is encoded with the same key, is omitted by WrapCore.
+func SourceLocationFromFrame(f runtime.Frame) zap.Field {
+	return zap.Object(SourceLocationKey, sourceLocation{
+		File:     f.File,
+		Line:     f.Line,
+		Function: f.Function,
+	})
+}
+
+// newSourceLocationEncoder returns a encoder for SourceLocation.
+// If callerFile is not nil, it returns the file path of the caller.
 //
 // https://cloud.google.com/logging/docs/reference/v2/rest/v2/LogEntry#logentrysourcelocation
-func sourceLocationEncoder(caller zapcore.EntryCaller, enc zapcore.PrimitiveArrayEncoder) {
-	if aenc, ok := enc.(zapcore.ArrayEncoder); ok {
-		aenc.AppendObject(sourceLocation{
-			File:     caller.File,
-			Line:     caller.Line,
-			Function: caller.Function,
-		})
-	} else {
-		enc.AppendString(caller.TrimmedPath())
+func newSourceLocationEncoder(callerFile func(zapcore.EntryCaller) string) zapcore.CallerEncoder {
+	return func(caller zapcore.EntryCaller, enc zapcore.PrimitiveArrayEncoder) {
+		if aenc, ok := enc.(zapcore.ArrayEncoder); ok {
+			file := caller.File
+			if callerFile != nil {
+				file = callerFile(caller)
+			}
+			aenc.AppendObject(sourceLocation{
+				File:     file,
+				Line:     caller.Line,
+				Function: caller.Function,
+			})
+		} else {
+			enc.AppendString(caller.TrimmedPath())
+		}
 	}
 }
 
@@ -77,28 +103,234 @@ func timestampEncoder(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
 	}
 }
 
+// reflectedEncoder is a zapcore.ReflectedEncoder that encodes values with encoding/json.
+// Values that can't be represented in JSON, e.g. containing NaN or ±Inf floats,
+// are encoded as the string formatted by fmt, since otherwise only the error is logged.
+// Float fields such as zap.Float64 are already encoded as "NaN", "+Inf" or "-Inf" by the JSON encoder.
+type reflectedEncoder struct {
+	enc *json.Encoder
+}
+
+func newReflectedEncoder(w io.Writer) zapcore.ReflectedEncoder {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return reflectedEncoder{enc: enc}
+}
+
+func (e reflectedEncoder) Encode(v interface{}) error {
+	err := e.enc.Encode(v)
+	var uerr *json.UnsupportedValueError
+	if errors.As(err, &uerr) {
+		return e.enc.Encode(fmt.Sprintf("%+v", v))
+	}
+	return err
+}
+
+// Encoding is the name of the JSON encoding of zap,
+// except that the message key is omitted for entries with an empty message,
+// so that an entry logged with Payload has only the structured payload.
+// It's used by the loggers built with WithOmitEmptyMessage, or by setting it to zap.Config.Encoding.
+const Encoding = "cloudlogging-json"
+
+func init() {
+	if err := zap.RegisterEncoder(Encoding, newEncoder); err != nil {
+		panic(err)
+	}
+}
+
+// WithOmitEmptyMessage returns an Option that encodes the entries with Encoding
+// instead of the JSON encoding of zap, which logs the message key even for entries with an empty message.
+// The other encodings set with WithConfig are kept as is.
+func WithOmitEmptyMessage() Option {
+	return optionFunc(func(s *settings) {
+		s.omitEmptyMessage = true
+	})
+}
+
+// encoder is a JSON encoder that omits the message key for entries with an empty message.
+type encoder struct {
+	zapcore.Encoder
+	// emptyMessage is the encoded message key with an empty message.
+	emptyMessage []byte
+}
+
+func newEncoder(cfg zapcore.EncoderConfig) (zapcore.Encoder, error) {
+	enc := &encoder{Encoder: zapcore.NewJSONEncoder(cfg)}
+	if cfg.MessageKey != "" && cfg.MessageKey != zapcore.OmitKey {
+		key, err := json.Marshal(cfg.MessageKey)
+		if err != nil {
+			return nil, err
+		}
+		enc.emptyMessage = append(key, `:""`...)
+	}
+	return enc, nil
+}
+
+func (e *encoder) Clone() zapcore.Encoder {
+	return &encoder{
+		Encoder:      e.Encoder.Clone(),
+		emptyMessage: e.emptyMessage,
+	}
+}
+
+func (e *encoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	buf, err := e.Encoder.EncodeEntry(ent, fields)
+	if err != nil || ent.Message != "" || e.emptyMessage == nil {
+		return buf, err
+	}
+
+	// The message is encoded before the fields, and quotes in strings are escaped,
+	// so the first occurrence is the message.
+	b := buf.Bytes()
+	start := bytes.Index(b, e.emptyMessage)
+	if start < 0 {
+		return buf, nil
+	}
+	end := start + len(e.emptyMessage)
+	if end < len(b) && b[end] == ',' {
+		end++
+	} else if start > 0 && b[start-1] == ',' {
+		start--
+	}
+	head, tail := string(b[:start]), string(b[end:])
+	buf.Reset()
+	buf.AppendString(head)
+	buf.AppendString(tail)
+	return buf, nil
+}
+
+// encoderConfig encodes entries in the format of structured logging.
+// The stacktrace key is omitted for entries without a stack trace,
+// which zap only captures at ERROR or above (WARNING or above in development).
+// Reflected values are encoded by reflectedEncoder, since entries with invalid JSON are rejected by Cloud Logging.
+//
 // https://cloud.google.com/logging/docs/structured-logging
 var encoderConfig = zapcore.EncoderConfig{
-	MessageKey:     "message",
-	LevelKey:       "severity",
-	TimeKey:        "timestamp",
-	NameKey:        "logger",
-	CallerKey:      "logging.googleapis.com/sourceLocation",
-	FunctionKey:    zapcore.OmitKey,
-	StacktraceKey:  "stacktrace",
-	LineEnding:     zapcore.DefaultLineEnding,
-	EncodeLevel:    severityEncoder,
-	EncodeTime:     timestampEncoder,
-	EncodeDuration: zapcore.MillisDurationEncoder,
-	EncodeCaller:   sourceLocationEncoder,
+	MessageKey:          MessageKey,
+	LevelKey:            SeverityKey,
+	TimeKey:             TimestampKey,
+	NameKey:             LoggerKey,
+	CallerKey:           SourceLocationKey,
+	FunctionKey:         zapcore.OmitKey,
+	StacktraceKey:       StacktraceKey,
+	LineEnding:          zapcore.DefaultLineEnding,
+	EncodeLevel:         severityEncoder,
+	EncodeTime:          timestampEncoder,
+	EncodeDuration:      zapcore.MillisDurationEncoder,
+	EncodeCaller:        newSourceLocationEncoder(nil),
+	NewReflectedEncoder: newReflectedEncoder,
+}
+
+// encoderOptions holds the settings applied by EncoderOptions.
+type encoderOptions struct {
+	callerMode     CallerMode
+	trimCallerPath func(string) string
+	encodeDuration zapcore.DurationEncoder
+	// nameKey is nil if it's not set, since zapcore.OmitKey is empty.
+	nameKey  *string
+	timeZone *time.Location
+}
+
+// An EncoderOption configures a zapcore.EncoderConfig created by
+// NewProductionEncoderConfig or NewDevelopmentEncoderConfig.
+type EncoderOption func(*encoderOptions)
+
+// WithCallerPathTrimmer sets a function that trims the file path of sourceLocation.
+// By default, the full path is used.
+func WithCallerPathTrimmer(trim func(string) string) EncoderOption {
+	return func(o *encoderOptions) {
+		o.trimCallerPath = trim
+	}
+}
+
+// A CallerMode is the form of the file path of sourceLocation.
+type CallerMode int
+
+const (
+	// CallerAbsolute is the full path of the file at compile time, e.g. "/home/user/src/app/db/conn.go".
+	CallerAbsolute CallerMode = iota
+	// CallerModuleRelative is the path relative to the root of the main module, e.g. "db/conn.go".
+	// For a file of other modules, it's the import path of the package and the file name,
+	// e.g. "github.com/lib/pq/conn.go".
+	CallerModuleRelative
+	// CallerBase is the file name, e.g. "conn.go".
+	CallerBase
+)
+
+// WithCallerMode sets the form of the file path of sourceLocation.
+// By default, it's CallerAbsolute.
+// The path is trimmed by the function set by WithCallerPathTrimmer after it's formed.
+func WithCallerMode(mode CallerMode) EncoderOption {
+	return func(o *encoderOptions) {
+		o.callerMode = mode
+	}
+}
+
+// WithDurationEncoder sets a zapcore.DurationEncoder for duration fields.
+// By default, zapcore.MillisDurationEncoder is used.
+func WithDurationEncoder(enc zapcore.DurationEncoder) EncoderOption {
+	return func(o *encoderOptions) {
+		o.encodeDuration = enc
+	}
+}
+
+// WithNameKey sets the key of the logger name.
+// By default, the name is logged as logger.
+func WithNameKey(key string) EncoderOption {
+	return func(o *encoderOptions) {
+		o.nameKey = &key
+	}
+}
+
+// WithoutName omits the logger name.
+func WithoutName() EncoderOption {
+	return WithNameKey(zapcore.OmitKey)
+}
+
+// WithTimeZone encodes the timestamp as a RFC 3339 string in loc, e.g. "2006-01-02T15:04:05.999999999+09:00",
+// for reading the local files of NewHybridConfig. Cloud Logging reads the offset, so the timestamp is the same.
+// By default, the timestamp is encoded as the seconds and nanos since the Unix epoch, which has no time zone.
+func WithTimeZone(loc *time.Location) EncoderOption {
+	return func(o *encoderOptions) {
+		o.timeZone = loc
+	}
+}
+
+// timeZoneEncoder returns a zapcore.TimeEncoder that encodes times as RFC 3339 strings in loc.
+func timeZoneEncoder(loc *time.Location) zapcore.TimeEncoder {
+	return func(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+		zapcore.RFC3339NanoTimeEncoder(t.In(loc), enc)
+	}
+}
+
+func newEncoderConfig(opts []EncoderOption) zapcore.EncoderConfig {
+	var o encoderOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	cfg := encoderConfig
+	if o.callerMode != CallerAbsolute || o.trimCallerPath != nil {
+		cfg.EncodeCaller = newSourceLocationEncoder(callerFile(o.callerMode, o.trimCallerPath))
+	}
+	if o.encodeDuration != nil {
+		cfg.EncodeDuration = o.encodeDuration
+	}
+	if o.nameKey != nil {
+		cfg.NameKey = *o.nameKey
+	}
+	if o.timeZone != nil {
+		cfg.EncodeTime = timeZoneEncoder(o.timeZone)
+	}
+	return cfg
 }
 
 // NewProductionEncoderConfig returns a zapcore.EncoderConfig for production environments.
-func NewProductionEncoderConfig() zapcore.EncoderConfig {
-	return encoderConfig
+func NewProductionEncoderConfig(opts ...EncoderOption) zapcore.EncoderConfig {
+	return newEncoderConfig(opts)
 }
 
 // NewDevelopmentEncoderConfig returns a zapcore.EncoderConfig for development environments.
-func NewDevelopmentEncoderConfig() zapcore.EncoderConfig {
-	return encoderConfig
+func NewDevelopmentEncoderConfig(opts ...EncoderOption) zapcore.EncoderConfig {
+	return newEncoderConfig(opts)
 }
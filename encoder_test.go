@@ -0,0 +1,311 @@
+package zapcloudlogging
+
+import (
+	"encoding/json"
+	"math"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestStacktraceOmitted(t *testing.T) {
+	debug := WithLevelRef(zap.NewAtomicLevelAt(zapcore.DebugLevel))
+	tests := []struct {
+		name           string
+		development    bool
+		level          zapcore.Level
+		wantStacktrace bool
+	}{
+		{name: "production DEBUG", level: zapcore.DebugLevel},
+		{name: "production INFO", level: zapcore.InfoLevel},
+		{name: "production WARNING", level: zapcore.WarnLevel},
+		{name: "production ERROR", level: zapcore.ErrorLevel, wantStacktrace: true},
+		{name: "development DEBUG", development: true, level: zapcore.DebugLevel},
+		{name: "development INFO", development: true, level: zapcore.InfoLevel},
+		{name: "development WARNING", development: true, level: zapcore.WarnLevel, wantStacktrace: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				logger *zap.Logger
+				sink   *testSink
+			)
+			if tt.development {
+				logger, sink = buildTestLogger(t, NewDevelopmentConfig(), NewDevelopmentEncoderConfig, debug)
+			} else {
+				logger, sink = newTestLogger(t, debug)
+			}
+			logger.Check(tt.level, "msg").Write()
+
+			entries := sink.entries(t)
+			if len(entries) != 1 {
+				t.Fatalf("got %d entries, want 1", len(entries))
+			}
+			if _, ok := entries[0][StacktraceKey]; ok != tt.wantStacktrace {
+				t.Errorf("entry = %v, want %s: %v", entries[0], StacktraceKey, tt.wantStacktrace)
+			}
+		})
+	}
+}
+
+// encodeEntry returns ent and fields encoded as a JSON object with cfg.
+func encodeEntry(t testing.TB, cfg zapcore.EncoderConfig, ent zapcore.Entry, fields ...zap.Field) map[string]any {
+	t.Helper()
+	enc, err := newEncoder(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf, err := enc.EncodeEntry(ent, fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer buf.Free()
+	var got map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("%v: %s", err, buf.Bytes())
+	}
+	return got
+}
+
+// testCaller is the caller of the entries encoded by the tests.
+var testCaller = zapcore.EntryCaller{
+	Defined:  true,
+	File:     "/home/user/src/app/db/conn.go",
+	Line:     42,
+	Function: "example.com/app/db.(*Conn).Query",
+}
+
+func TestWithCallerPathTrimmer(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []EncoderOption
+		want string
+	}{
+		{name: "default", want: "/home/user/src/app/db/conn.go"},
+		{name: "nil", opts: []EncoderOption{WithCallerPathTrimmer(nil)}, want: "/home/user/src/app/db/conn.go"},
+		{
+			name: "trimmed",
+			opts: []EncoderOption{WithCallerPathTrimmer(func(file string) string {
+				return strings.TrimPrefix(file, "/home/user/src/")
+			})},
+			want: "app/db/conn.go",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := encodeEntry(t, NewProductionEncoderConfig(tt.opts...), zapcore.Entry{Caller: testCaller})
+			assertJSON(t, SourceLocationKey, got[SourceLocationKey], map[string]any{
+				"file":     tt.want,
+				"line":     "42",
+				"function": testCaller.Function,
+			})
+		})
+	}
+}
+
+func TestWithDurationEncoder(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []EncoderOption
+		want any
+	}{
+		{name: "default", want: 1500},
+		{name: "string", opts: []EncoderOption{WithDurationEncoder(zapcore.StringDurationEncoder)}, want: "1.5s"},
+		{name: "seconds", opts: []EncoderOption{WithDurationEncoder(zapcore.SecondsDurationEncoder)}, want: 1.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := encodeEntry(t, NewProductionEncoderConfig(tt.opts...), zapcore.Entry{}, zap.Duration("elapsed", 1500*time.Millisecond))
+			assertJSON(t, "elapsed", got["elapsed"], tt.want)
+		})
+	}
+}
+
+func TestSourceLocationFromFrame(t *testing.T) {
+	frame := runtime.Frame{File: "/src/app/worker.go", Line: 7, Function: "example.com/app.work"}
+	want := map[string]any{"file": "/src/app/worker.go", "line": "7", "function": "example.com/app.work"}
+
+	tests := []struct {
+		name string
+		log  func(logger *zap.Logger)
+	}{
+		{name: "field", log: func(logger *zap.Logger) { logger.Info("msg", SourceLocationFromFrame(frame)) }},
+		{name: "with", log: func(logger *zap.Logger) { logger.With(SourceLocationFromFrame(frame)).Info("msg") }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, sink := newTestLogger(t, withCaller)
+			tt.log(logger)
+
+			out := sink.String()
+			if n := strings.Count(out, SourceLocationKey); n != 1 {
+				t.Errorf("output = %s, want a single %s", out, SourceLocationKey)
+			}
+			assertJSON(t, SourceLocationKey, sink.entries(t)[0][SourceLocationKey], want)
+		})
+	}
+}
+
+func TestReflectedEncoder(t *testing.T) {
+	tests := []struct {
+		name string
+		v    any
+		want any
+	}{
+		{name: "map", v: map[string]float64{"ratio": 0.5}, want: map[string]any{"ratio": 0.5}},
+		{name: "NaN", v: map[string]float64{"ratio": math.NaN()}, want: "map[ratio:NaN]"},
+		{name: "Inf", v: []float64{1, math.Inf(1)}, want: "[1 +Inf]"},
+		{name: "struct", v: struct{ X float64 }{math.Inf(-1)}, want: "{X:-Inf}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := encodeEntry(t, NewProductionEncoderConfig(), zapcore.Entry{}, zap.Reflect("value", tt.v))
+			assertJSON(t, "value", got["value"], tt.want)
+			if _, ok := got["valueError"]; ok {
+				t.Errorf("entry = %v, want no valueError", got)
+			}
+		})
+	}
+}
+
+func TestEncoderEmptyMessage(t *testing.T) {
+	noTime := func(cfg zapcore.EncoderConfig) zapcore.EncoderConfig {
+		cfg.TimeKey = zapcore.OmitKey
+		return cfg
+	}
+	tests := []struct {
+		name   string
+		cfg    zapcore.EncoderConfig
+		msg    string
+		fields []zap.Field
+		want   string
+	}{
+		{
+			name:   "message",
+			cfg:    noTime(NewProductionEncoderConfig()),
+			msg:    "msg",
+			fields: []zap.Field{zap.Int("n", 1)},
+			want:   `{"severity":"INFO","message":"msg","n":1}`,
+		},
+		{
+			name:   "empty message",
+			cfg:    noTime(NewProductionEncoderConfig()),
+			fields: []zap.Field{zap.Int("n", 1)},
+			want:   `{"severity":"INFO","n":1}`,
+		},
+		{
+			name: "empty message without fields",
+			cfg:  noTime(NewProductionEncoderConfig()),
+			want: `{"severity":"INFO"}`,
+		},
+		{
+			name: "only message",
+			cfg: func() zapcore.EncoderConfig {
+				cfg := noTime(NewProductionEncoderConfig())
+				cfg.LevelKey = zapcore.OmitKey
+				return cfg
+			}(),
+			want: `{}`,
+		},
+		{
+			name:   "empty field value",
+			cfg:    noTime(NewProductionEncoderConfig()),
+			fields: []zap.Field{zap.String("message", "")},
+			want:   `{"severity":"INFO","message":""}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			enc, err := newEncoder(tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			buf, err := enc.EncodeEntry(zapcore.Entry{Level: zapcore.InfoLevel, Message: tt.msg}, tt.fields)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer buf.Free()
+			if got := strings.TrimSuffix(buf.String(), "\n"); got != tt.want {
+				t.Errorf("EncodeEntry() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithOmitEmptyMessage(t *testing.T) {
+	console := WithConfig(func(cfg *zap.Config) {
+		cfg.Encoding = "console"
+		cfg.EncoderConfig.LevelKey = zapcore.OmitKey
+	})
+	tests := []struct {
+		name string
+		opts []Option
+		want string
+	}{
+		{name: "default", want: `{"severity":"INFO","message":"","n":1}` + "\n"},
+		{name: "omitted", opts: []Option{WithOmitEmptyMessage()}, want: `{"severity":"INFO","n":1}` + "\n"},
+		{name: "forced JSON", opts: []Option{console, WithForceJSON(), WithOmitEmptyMessage()}, want: `{"n":1}` + "\n"},
+		{name: "console kept", opts: []Option{console, WithOmitEmptyMessage()}, want: `{"n": 1}` + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, sink := newTestLogger(t, tt.opts...)
+			logger.Info("", zap.Int("n", 1))
+			if got := sink.String(); got != tt.want {
+				t.Errorf("output = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithNameKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []EncoderOption
+		wantKey string
+	}{
+		{name: "default", wantKey: LoggerKey},
+		{name: "renamed", opts: []EncoderOption{WithNameKey("component")}, wantKey: "component"},
+		{name: "omitted", opts: []EncoderOption{WithoutName()}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewProductionEncoderConfig(tt.opts...)
+			cfg.TimeKey = zapcore.OmitKey
+			got := encodeEntry(t, cfg, zapcore.Entry{LoggerName: "db.pool", Message: "msg"})
+			want := map[string]any{SeverityKey: "INFO", MessageKey: "msg"}
+			if tt.wantKey != "" {
+				want[tt.wantKey] = "db.pool"
+			}
+			assertJSON(t, "entry", got, want)
+		})
+	}
+}
+
+func TestWithTimeZone(t *testing.T) {
+	ts := time.Date(2024, 3, 1, 12, 30, 45, 123456789, time.UTC)
+	tests := []struct {
+		name string
+		opts []EncoderOption
+		want any
+	}{
+		{name: "default", want: map[string]any{"seconds": ts.Unix(), "nanos": 123456789}},
+		{name: "UTC", opts: []EncoderOption{WithTimeZone(time.UTC)}, want: "2024-03-01T12:30:45.123456789Z"},
+		{
+			name: "fixed",
+			opts: []EncoderOption{WithTimeZone(time.FixedZone("JST", 9*60*60))},
+			want: "2024-03-01T21:30:45.123456789+09:00",
+		},
+		{name: "nil", opts: []EncoderOption{WithTimeZone(nil)}, want: map[string]any{"seconds": ts.Unix(), "nanos": 123456789}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := encodeEntry(t, NewProductionEncoderConfig(tt.opts...), zapcore.Entry{Time: ts})
+			assertJSON(t, TimestampKey, got[TimestampKey], tt.want)
+		})
+	}
+}
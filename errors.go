@@ -0,0 +1,81 @@
+package zapcloudlogging
+
+import (
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// errorChain is the value of a field created by Error.
+type errorChain struct {
+	err error
+}
+
+func (c errorChain) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("error", c.err.Error())
+	return enc.AddArray("error_chain", zapcore.ArrayMarshalerFunc(func(enc zapcore.ArrayEncoder) error {
+		for err := c.err; err != nil; err = errors.Unwrap(err) {
+			err := err
+			if aerr := enc.AppendObject(zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+				enc.AddString("type", fmt.Sprintf("%T", err))
+				enc.AddString("message", err.Error())
+				return nil
+			})); aerr != nil {
+				return aerr
+			}
+		}
+		return nil
+	}))
+}
+
+// Error returns a zap.Field that logs the message of err as the error field like zap.Error,
+// and the type and the message of each error in the chain unwrapped by errors.Unwrap as the error_chain array.
+// If err is nil, the field is skipped.
+func Error(err error) zap.Field {
+	if err == nil {
+		return zap.Skip()
+	}
+	return zap.Inline(errorChain{err: err})
+}
+
+// errorOf returns the error logged by f, created by zap.Error, zap.NamedError or Error.
+func errorOf(f zapcore.Field) (error, bool) {
+	switch v := f.Interface.(type) {
+	case error:
+		return v, f.Type == zapcore.ErrorType
+	case errorChain:
+		return v.err, f.Type == zapcore.InlineMarshalerType
+	}
+	return nil, false
+}
+
+// errorList is the value of a field created by Errors, without nil errors.
+type errorList []error
+
+func (errs errorList) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddInt("count", len(errs))
+	return enc.AddArray("messages", zapcore.ArrayMarshalerFunc(func(enc zapcore.ArrayEncoder) error {
+		for _, err := range errs {
+			enc.AppendString(err.Error())
+		}
+		return nil
+	}))
+}
+
+// Errors returns a zap.Field that logs the number and the messages of errs as the key object,
+// e.g. of the errors of parallel tasks, so that each message can be queried, unlike the messages joined in a string.
+// The nil errors are skipped, and if all of them are nil, the field is skipped.
+func Errors(key string, errs []error) zap.Field {
+	var list errorList
+	for _, err := range errs {
+		if err != nil {
+			list = append(list, err)
+		}
+	}
+	if len(list) == 0 {
+		return zap.Skip()
+	}
+	return zap.Object(key, list)
+}
@@ -0,0 +1,80 @@
+package zapcloudlogging
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestError(t *testing.T) {
+	base := errors.New("not found")
+	tests := []struct {
+		name string
+		err  error
+		want map[string]any
+	}{
+		{name: "nil", want: map[string]any{SeverityKey: "INFO"}},
+		{
+			name: "single",
+			err:  base,
+			want: map[string]any{
+				SeverityKey: "INFO",
+				"error":     "not found",
+				"error_chain": []map[string]any{
+					{"type": "*errors.errorString", "message": "not found"},
+				},
+			},
+		},
+		{
+			name: "wrapped",
+			err:  fmt.Errorf("load: %w", &fs.PathError{Op: "open", Path: "a.txt", Err: fs.ErrNotExist}),
+			want: map[string]any{
+				SeverityKey: "INFO",
+				"error":     "load: open a.txt: file does not exist",
+				"error_chain": []map[string]any{
+					{"type": "*fmt.wrapError", "message": "load: open a.txt: file does not exist"},
+					{"type": "*fs.PathError", "message": "open a.txt: file does not exist"},
+					{"type": "*errors.errorString", "message": "file does not exist"},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertJSON(t, "entry", encodeFields(t, Error(tt.err)), tt.want)
+		})
+	}
+}
+
+func TestErrorClassified(t *testing.T) {
+	errNotFound := errors.New("not found")
+	logger, sink := newTestLogger(t, WithErrorSeverityClassifier(func(err error) (zapcore.Level, bool) {
+		return zapcore.WarnLevel, errors.Is(err, errNotFound)
+	}))
+	logger.Error("msg", Error(fmt.Errorf("get: %w", errNotFound)))
+
+	assertJSON(t, SeverityKey, sink.entries(t)[0][SeverityKey], "WARNING")
+}
+
+func TestErrors(t *testing.T) {
+	errA, errB := errors.New("task a: timeout"), errors.New("task b: not found")
+	tests := []struct {
+		name string
+		errs []error
+		want any
+	}{
+		{name: "errors", errs: []error{errA, errB}, want: map[string]any{"count": 2, "messages": []string{errA.Error(), errB.Error()}}},
+		{name: "mixed", errs: []error{nil, errA, nil}, want: map[string]any{"count": 1, "messages": []string{errA.Error()}}},
+		{name: "all nil", errs: []error{nil, nil}, want: nil},
+		{name: "empty", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := encodeFields(t, Errors("task_errors", tt.errs))
+			assertJSON(t, "task_errors", got["task_errors"], tt.want)
+		})
+	}
+}
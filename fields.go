@@ -0,0 +1,747 @@
+package zapcloudlogging
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const auditLogType = "type.googleapis.com/google.cloud.audit.AuditLog"
+
+type auditLog struct {
+	MethodName     string
+	ResourceName   string
+	PrincipalEmail string
+}
+
+func (l auditLog) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("@type", auditLogType)
+	enc.AddString("methodName", l.MethodName)
+	enc.AddString("resourceName", l.ResourceName)
+	return enc.AddObject("authenticationInfo", zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+		enc.AddString("principalEmail", l.PrincipalEmail)
+		return nil
+	}))
+}
+
+// AuditEvent returns a zap.Field for an application-level audit event,
+// shaped like the protoPayload of Cloud Audit Logs.
+//
+// The entry is still written to jsonPayload, so it is not a real Cloud Audit Log:
+// it is not routed to the audit log buckets, and only the fields above are populated.
+//
+// https://cloud.google.com/logging/docs/reference/audit/auditlog/rest/Shared.Types/AuditLog
+func AuditEvent(methodName, resourceName, principal string) zap.Field {
+	return zap.Object("protoPayload", auditLog{
+		MethodName:     methodName,
+		ResourceName:   resourceName,
+		PrincipalEmail: principal,
+	})
+}
+
+type truncatedArray struct {
+	v        reflect.Value
+	maxItems int
+}
+
+func (a truncatedArray) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	n := a.v.Len()
+	for i := 0; i < n && i < a.maxItems; i++ {
+		if err := enc.AppendReflected(a.v.Index(i).Interface()); err != nil {
+			return err
+		}
+	}
+	if n > a.maxItems {
+		enc.AppendString("..." + strconv.Itoa(n-a.maxItems) + " more")
+	}
+	return nil
+}
+
+type truncatedMap struct {
+	v        reflect.Value
+	maxItems int
+}
+
+func (m truncatedMap) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	keys := m.v.MapKeys()
+	names := make([]string, len(keys))
+	values := make(map[string]reflect.Value, len(keys))
+	for i, k := range keys {
+		names[i] = fmt.Sprint(k.Interface())
+		values[names[i]] = m.v.MapIndex(k)
+	}
+	// Sort the keys so that the same items are kept every time.
+	sort.Strings(names)
+
+	for i := 0; i < len(names) && i < m.maxItems; i++ {
+		if err := enc.AddReflected(names[i], values[names[i]].Interface()); err != nil {
+			return err
+		}
+	}
+	if len(names) > m.maxItems {
+		enc.AddString("...", strconv.Itoa(len(names)-m.maxItems)+" more")
+	}
+	return nil
+}
+
+// Truncated returns a zap.Field that logs at most maxItems items of a slice, an array or a map.
+// If v has more items, a "...N more" marker is appended; map items are kept in key order.
+// Values of other kinds are logged as zap.Any does.
+//
+// This keeps entries within the size limit of Cloud Logging while preserving a sample.
+//
+// https://cloud.google.com/logging/quotas#log-limits
+func Truncated(key string, v any, maxItems int) zap.Field {
+	if maxItems < 0 {
+		maxItems = 0
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice:
+		if rv.IsNil() {
+			break
+		}
+		fallthrough
+	case reflect.Array:
+		return zap.Array(key, truncatedArray{v: rv, maxItems: maxItems})
+	case reflect.Map:
+		if rv.IsNil() {
+			break
+		}
+		return zap.Object(key, truncatedMap{v: rv, maxItems: maxItems})
+	}
+	return zap.Any(key, v)
+}
+
+type validationErrors map[string]string
+
+func (errs validationErrors) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	paths := make([]string, 0, len(errs))
+	for path := range errs {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		enc.AddString(path, errs[path])
+	}
+	return nil
+}
+
+// ValidationErrors returns a zap.Field that logs error messages keyed by field path
+// under the validationErrors key, ordered by path, and sets the validation_error label.
+//
+// Validation errors are usually caused by clients, so log them at WARNING;
+// entries at ERROR or above with a stack trace are reported to Error Reporting.
+func ValidationErrors(errs map[string]string) zap.Field {
+	return group(
+		zap.Object("validationErrors", validationErrors(errs)),
+		Label("validation_error", "true"),
+	)
+}
+
+type body struct {
+	Content   []byte
+	Truncated bool
+}
+
+func (b body) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddByteString("content", b.Content)
+	enc.AddBool("truncated", b.Truncated)
+	return nil
+}
+
+// Body returns a zap.Field that logs up to maxBytes of r, e.g. a request body, with whether it was truncated,
+// and a reader that reads the whole content of r, including the logged part.
+// If r is an io.Closer, the returned reader also implements io.Closer and closes r.
+func Body(key string, r io.Reader, maxBytes int) (zap.Field, io.Reader) {
+	if maxBytes < 0 {
+		maxBytes = 0
+	}
+
+	// Read one more byte to know whether the content is truncated.
+	buf := make([]byte, maxBytes+1)
+	n, err := io.ReadFull(r, buf)
+	buf = buf[:n]
+
+	var rest io.Reader
+	switch err {
+	case nil:
+		rest = io.MultiReader(bytes.NewReader(buf), r)
+	case io.EOF, io.ErrUnexpectedEOF:
+		rest = bytes.NewReader(buf)
+	default:
+		rest = io.MultiReader(bytes.NewReader(buf), errReader{err})
+	}
+	if c, ok := r.(io.Closer); ok {
+		rest = readCloser{Reader: rest, Closer: c}
+	}
+
+	b := body{Content: buf}
+	if n > maxBytes {
+		b.Content, b.Truncated = buf[:maxBytes], true
+	}
+	return zap.Object(key, b), rest
+}
+
+type errReader struct {
+	err error
+}
+
+func (r errReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// Payload returns an empty message and the fields of v,
+// so that the entry is logged with v as its structured payload and without a message:
+//
+//	msg, fields := zapcloudlogging.Payload(v)
+//	logger.Info(msg, fields...)
+//
+// v is logged inline if it implements zapcore.ObjectMarshaler,
+// otherwise the properties of its JSON representation are logged as fields, ordered by key.
+// If v isn't represented as a JSON object, it's logged as the payload field.
+//
+// The message key is omitted by the loggers built with WithOmitEmptyMessage, see Encoding.
+func Payload(v any) (string, []zap.Field) {
+	if m, ok := v.(zapcore.ObjectMarshaler); ok {
+		return "", []zap.Field{zap.Inline(m)}
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", []zap.Field{zap.Any("payload", v)}
+	}
+	fields, ok := jsonObjectFields(b)
+	if !ok {
+		return "", []zap.Field{zap.Any("payload", v)}
+	}
+	return "", fields
+}
+
+// jsonObjectFields returns the properties of the JSON object b as fields, ordered by key.
+// If b isn't a JSON object, it returns false.
+func jsonObjectFields(b []byte) ([]zap.Field, bool) {
+	var props map[string]json.RawMessage
+	if err := json.Unmarshal(b, &props); err != nil || props == nil {
+		return nil, false
+	}
+
+	keys := make([]string, 0, len(props))
+	for k := range props {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	fields := make([]zap.Field, len(keys))
+	for i, k := range keys {
+		fields[i] = zap.Reflect(k, props[k])
+	}
+	return fields, true
+}
+
+type retryAttempt struct {
+	attempt     int
+	maxAttempts int
+	nextDelay   time.Duration
+}
+
+func (r retryAttempt) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddInt("attempt", r.attempt)
+	enc.AddInt("max_attempts", r.maxAttempts)
+	remaining := r.maxAttempts - r.attempt
+	if remaining < 0 {
+		remaining = 0
+	}
+	enc.AddInt("remaining", remaining)
+	enc.AddString("next_delay", formatDuration(r.nextDelay))
+	return nil
+}
+
+// RetryAttempt returns the fields for a failed attempt of an operation retried up to maxAttempts times:
+// the retry object with the attempt number counted from 1, the number of the remaining attempts
+// and the delay until the next attempt as a google.protobuf.Duration, and lastErr as the error field.
+func RetryAttempt(attempt, maxAttempts int, lastErr error, nextDelay time.Duration) []zap.Field {
+	return []zap.Field{
+		zap.Object("retry", retryAttempt{
+			attempt:     attempt,
+			maxAttempts: maxAttempts,
+			nextDelay:   nextDelay,
+		}),
+		zap.Error(lastErr),
+	}
+}
+
+// Enum returns a zap.Field that logs the name of an enum value returned by its String method,
+// instead of the number logged by zap.Any for integer types, so that filters can match the name.
+// If v is nil, the field is skipped; if v is a nil pointer whose String method panics, "<nil>" is logged.
+func Enum(key string, v fmt.Stringer) zap.Field {
+	if v == nil {
+		return zap.Skip()
+	}
+	return zap.Stringer(key, v)
+}
+
+type featureFlag struct {
+	name   string
+	value  any
+	reason string
+}
+
+func (f featureFlag) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("name", f.name)
+	if err := enc.AddReflected("value", f.value); err != nil {
+		return err
+	}
+	if f.reason != "" {
+		enc.AddString("reason", f.reason)
+	}
+	return nil
+}
+
+// FeatureFlag returns the fields for an evaluation of a feature flag with the reason of the value,
+// e.g. "TARGETING_MATCH": the name and the typed value and reason as the feature_flag object,
+// and the name and the value converted like LabelsAny as the feature_flag and feature_flag_value labels.
+func FeatureFlag(name string, value any, reason string) []zap.Field {
+	return []zap.Field{
+		Labels(map[string]string{
+			"feature_flag":       name,
+			"feature_flag_value": labelValue(value),
+		}),
+		zap.Object("feature_flag", featureFlag{name: name, value: value, reason: reason}),
+	}
+}
+
+type cacheLookup struct {
+	name     string
+	hit      bool
+	duration time.Duration
+}
+
+func (c cacheLookup) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("name", c.name)
+	enc.AddBool("hit", c.hit)
+	enc.AddString("duration", formatDuration(c.duration))
+	return nil
+}
+
+// Cache returns the fields for a lookup of the cache name completed in d:
+// the name as the cache label, and the name, whether it hit and the duration as a google.protobuf.Duration
+// as the cache object.
+//
+// The hit ratio can be monitored with logs-based counter metrics, e.g. counting the misses with the filter
+//
+//	labels.cache="users" AND jsonPayload.cache.hit=false
+//
+// https://cloud.google.com/logging/docs/logs-based-metrics/counter-metrics
+func Cache(name string, hit bool, d time.Duration) []zap.Field {
+	return []zap.Field{
+		Label("cache", name),
+		zap.Object("cache", cacheLookup{name: name, hit: hit, duration: d}),
+	}
+}
+
+type lockAcquisition struct {
+	name     string
+	acquired bool
+	waited   time.Duration
+}
+
+func (l lockAcquisition) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("name", l.name)
+	enc.AddBool("acquired", l.acquired)
+	enc.AddString("waited", formatDuration(l.waited))
+	return nil
+}
+
+// Lock returns the fields for an acquisition of the distributed lock name after waiting for waited:
+// the name as the lock label, and the name, whether it was acquired and the wait as a google.protobuf.Duration
+// as the lock object.
+func Lock(name string, acquired bool, waited time.Duration) []zap.Field {
+	return []zap.Field{
+		Label("lock", name),
+		zap.Object("lock", lockAcquisition{name: name, acquired: acquired, waited: waited}),
+	}
+}
+
+type jobSummary struct {
+	name      string
+	processed int
+	failed    int
+	d         time.Duration
+}
+
+func (j jobSummary) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("name", j.name)
+	enc.AddInt("processed", j.processed)
+	enc.AddInt("failed", j.failed)
+	enc.AddString("duration", formatDuration(j.d))
+	return nil
+}
+
+// JobSummary returns the fields for the completion of the batch job name, which processed processed items,
+// failed failed of them and took d: the name as the job label, the counts and d as a google.protobuf.Duration
+// as the job object, and the severity, ERROR if failed is greater than 0, otherwise INFO.
+//
+//	logger.Info("job finished", zapcloudlogging.JobSummary("reindex", processed, failed, time.Since(start))...)
+func JobSummary(name string, processed, failed int, d time.Duration) []zap.Field {
+	level := zapcore.InfoLevel
+	if failed > 0 {
+		level = zapcore.ErrorLevel
+	}
+	return []zap.Field{
+		Label("job", name),
+		zap.Object("job", jobSummary{name: name, processed: processed, failed: failed, d: d}),
+		Severity(level),
+	}
+}
+
+type publication struct {
+	topic     string
+	messageID string
+	latency   time.Duration
+}
+
+func (p publication) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("topic", p.topic)
+	enc.AddString("message_id", p.messageID)
+	enc.AddString("latency", formatDuration(p.latency))
+	return nil
+}
+
+// Publish returns the fields for a message published to topic, e.g. of Pub/Sub or Kafka, with messageID,
+// which took d to publish: the topic as the topic label, and the topic, messageID and d as a google.protobuf.Duration
+// as the publish object.
+func Publish(topic string, messageID string, d time.Duration) []zap.Field {
+	return []zap.Field{
+		Label("topic", topic),
+		zap.Object("publish", publication{topic: topic, messageID: messageID, latency: d}),
+	}
+}
+
+type circuitBreakerTransition struct {
+	name string
+	from string
+	to   string
+}
+
+func (t circuitBreakerTransition) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("name", t.name)
+	enc.AddString("from", t.from)
+	enc.AddString("to", t.to)
+	return nil
+}
+
+// CircuitBreaker returns the fields for a state transition of the circuit breaker name, e.g. from "closed" to "open":
+// the name as the circuit_breaker label, the name and the states as the circuit_breaker object,
+// and the severity, WARNING if the breaker opens, i.e. to is "open" case-insensitively, otherwise INFO,
+// e.g. to alert on the breakers opening.
+func CircuitBreaker(name string, from, to string) []zap.Field {
+	level := zapcore.InfoLevel
+	if strings.EqualFold(to, "open") {
+		level = zapcore.WarnLevel
+	}
+	return []zap.Field{
+		Label("circuit_breaker", name),
+		zap.Object("circuit_breaker", circuitBreakerTransition{name: name, from: from, to: to}),
+		Severity(level),
+	}
+}
+
+type rateLimitDecision struct {
+	key        string
+	allowed    bool
+	remaining  int
+	resetAfter time.Duration
+}
+
+func (r rateLimitDecision) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("key", r.key)
+	enc.AddBool("allowed", r.allowed)
+	enc.AddInt("remaining", r.remaining)
+	enc.AddString("reset_after", formatDuration(r.resetAfter))
+	return nil
+}
+
+// RateLimit returns the fields for a decision of a rate limiter on key, e.g. a client ID,
+// with the remaining quota and the duration until it's reset: the key as the rate_limit_key label,
+// the decision and resetAfter as a google.protobuf.Duration as the rate_limit object,
+// and the severity, WARNING if the request is denied, otherwise INFO.
+func RateLimit(key string, allowed bool, remaining int, resetAfter time.Duration) []zap.Field {
+	level := zapcore.InfoLevel
+	if !allowed {
+		level = zapcore.WarnLevel
+	}
+	return []zap.Field{
+		Label("rate_limit_key", key),
+		zap.Object("rate_limit", rateLimitDecision{key: key, allowed: allowed, remaining: remaining, resetAfter: resetAfter}),
+		Severity(level),
+	}
+}
+
+type netDial struct {
+	network string
+	address string
+	latency time.Duration
+}
+
+func (n netDial) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("network", n.network)
+	enc.AddString("address", n.address)
+	enc.AddString("latency", formatDuration(n.latency))
+	return nil
+}
+
+// NetDial returns the fields for a dial of address on network, e.g. by net.Dialer.DialContext, which took d
+// and failed with err: the address as the net_address label, and network, address and d as a google.protobuf.Duration
+// as the dial object. If err isn't nil, it's logged as the error field and the severity is WARNING.
+func NetDial(network, address string, d time.Duration, err error) []zap.Field {
+	fields := []zap.Field{
+		Label("net_address", address),
+		zap.Object("dial", netDial{network: network, address: address, latency: d}),
+	}
+	if err != nil {
+		fields = append(fields, zap.Error(err), Severity(zapcore.WarnLevel))
+	}
+	return fields
+}
+
+// WithStack returns fields followed by the stack trace of the caller as the stacktrace field,
+// e.g. to debug where a WARNING or INFO entry is logged from without lowering the level of zap.AddStacktrace:
+//
+//	logger.Warn("unexpected state", zapcloudlogging.WithStack(zap.String("state", s))...)
+//
+// The stack is captured when WithStack is called, so it costs only the entries it's used for,
+// even if they're dropped later. Use it only for entries below the level of zap.AddStacktrace,
+// since otherwise the entry has two stacktrace fields.
+func WithStack(fields ...zap.Field) []zap.Field {
+	// Skip WithStack itself.
+	return append(fields[:len(fields):len(fields)], zap.StackSkip(StacktraceKey, 1))
+}
+
+// A BytesMode is the rendering of the bytes logged by Bytes.
+type BytesMode int
+
+const (
+	// BytesBase64 renders bytes as a standard base64 string, like zap.Binary.
+	BytesBase64 BytesMode = iota
+	// BytesHex renders bytes as a lowercase hexadecimal string, e.g. for binary identifiers.
+	BytesHex
+	// BytesUTF8 renders bytes as a string, with invalid UTF-8 sequences replaced with U+FFFD.
+	BytesUTF8
+)
+
+// maxLoggedBytes is the maximum number of bytes logged by Bytes.
+const maxLoggedBytes = 1024
+
+// Bytes returns a zap.Field that logs b as a string rendered with mode, so that binary values are queried predictably.
+// If b is longer than 1024 bytes, the first 1024 bytes are logged, followed by a "...N more bytes" marker.
+func Bytes(key string, b []byte, mode BytesMode) zap.Field {
+	var more string
+	if len(b) > maxLoggedBytes {
+		more = "..." + strconv.Itoa(len(b)-maxLoggedBytes) + " more bytes"
+		b = b[:maxLoggedBytes]
+	}
+
+	var s string
+	switch mode {
+	case BytesHex:
+		s = hex.EncodeToString(b)
+	case BytesUTF8:
+		s = strings.ToValidUTF8(string(b), "\uFFFD")
+	default:
+		s = base64.StdEncoding.EncodeToString(b)
+	}
+	return zap.String(key, s+more)
+}
+
+type migration struct {
+	from    int
+	to      int
+	applied []string
+	d       time.Duration
+}
+
+func (m migration) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddInt("from_version", m.from)
+	enc.AddInt("to_version", m.to)
+	if err := enc.AddArray("applied", zapcore.ArrayMarshalerFunc(func(enc zapcore.ArrayEncoder) error {
+		for _, name := range m.applied {
+			enc.AppendString(name)
+		}
+		return nil
+	})); err != nil {
+		return err
+	}
+	enc.AddString("duration", formatDuration(m.d))
+	return nil
+}
+
+// Migration returns the fields for a schema migration from the version from to the version to,
+// which applied the migrations named applied in d, e.g. at startup: to as the schema_version label,
+// the versions, the names and d as a google.protobuf.Duration as the migration object, and the severity INFO.
+func Migration(from, to int, applied []string, d time.Duration) []zap.Field {
+	return []zap.Field{
+		Label("schema_version", strconv.Itoa(to)),
+		zap.Object("migration", migration{from: from, to: to, applied: applied, d: d}),
+		Severity(zapcore.InfoLevel),
+	}
+}
+
+// MigrationFailed returns the fields for a schema migration like Migration, except that it failed with err
+// after applying the migrations named applied: to is the version it was migrating to, but the schema_version label
+// is from, err is logged as the error field, and the severity is ERROR.
+func MigrationFailed(from, to int, applied []string, d time.Duration, err error) []zap.Field {
+	return []zap.Field{
+		Label("schema_version", strconv.Itoa(from)),
+		zap.Object("migration", migration{from: from, to: to, applied: applied, d: d}),
+		zap.Error(err),
+		Severity(zapcore.ErrorLevel),
+	}
+}
+
+// WorkerStarted returns the fields for the start of the background worker name, e.g. a goroutine consuming a queue:
+// the name as the worker label, and the name and the started event as the worker object.
+func WorkerStarted(name string) []zap.Field {
+	return []zap.Field{
+		Label("worker", name),
+		zap.Object("worker", zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+			enc.AddString("name", name)
+			enc.AddString("event", "started")
+			return nil
+		})),
+	}
+}
+
+// normalStopReasons are the reasons of WorkerStopped that don't indicate an error.
+var normalStopReasons = []string{"", "shutdown", "done", "completed", "context canceled"}
+
+// WorkerStopped returns the fields for the stop of the background worker name after running for d:
+// the name as the worker label, and the name, the stopped event, the reason and d as a google.protobuf.Duration
+// as the worker object. The severity is INFO if the reason is one of "", "shutdown", "done", "completed"
+// and "context canceled", the message of context.Canceled, case-insensitively; otherwise it's WARNING,
+// e.g. for err.Error() of an unexpected error.
+func WorkerStopped(name string, reason string, d time.Duration) []zap.Field {
+	level := zapcore.WarnLevel
+	for _, r := range normalStopReasons {
+		if strings.EqualFold(reason, r) {
+			level = zapcore.InfoLevel
+			break
+		}
+	}
+	return []zap.Field{
+		Label("worker", name),
+		zap.Object("worker", zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+			enc.AddString("name", name)
+			enc.AddString("event", "stopped")
+			if reason != "" {
+				enc.AddString("reason", reason)
+			}
+			enc.AddString("lifetime", formatDuration(d))
+			return nil
+		})),
+		Severity(level),
+	}
+}
+
+type configReload struct {
+	source  string
+	changed []string
+}
+
+func (r configReload) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("source", r.source)
+	return enc.AddArray("changed", zapcore.ArrayMarshalerFunc(func(enc zapcore.ArrayEncoder) error {
+		for _, key := range r.changed {
+			enc.AppendString(key)
+		}
+		return nil
+	}))
+}
+
+// ConfigReloaded returns the fields for a reload of the config from source, e.g. a file path or a secret name,
+// which changed the keys changed and failed with err: the source as the config_source label,
+// the source and the keys as the config_reload object, and the severity, ERROR if err isn't nil, otherwise INFO.
+// If err isn't nil, it's logged as the error field.
+func ConfigReloaded(source string, changed []string, err error) []zap.Field {
+	fields := []zap.Field{
+		Label("config_source", source),
+		zap.Object("config_reload", configReload{source: source, changed: changed}),
+	}
+	if err != nil {
+		return append(fields, zap.Error(err), Severity(zapcore.ErrorLevel))
+	}
+	return append(fields, Severity(zapcore.InfoLevel))
+}
+
+// tlsVersionNames are the names of the TLS versions of crypto/tls.
+var tlsVersionNames = map[uint16]string{
+	tls.VersionTLS10: "TLS 1.0",
+	tls.VersionTLS11: "TLS 1.1",
+	tls.VersionTLS12: "TLS 1.2",
+	tls.VersionTLS13: "TLS 1.3",
+}
+
+// tlsVersionName returns the name of the TLS version, or its hexadecimal value if it's unknown.
+func tlsVersionName(version uint16) string {
+	if name, ok := tlsVersionNames[version]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%04X", version)
+}
+
+type tlsHandshake struct {
+	version     uint16
+	cipherSuite uint16
+	serverName  string
+	latency     time.Duration
+}
+
+func (h tlsHandshake) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	if h.version != 0 {
+		enc.AddString("version", tlsVersionName(h.version))
+	}
+	if h.cipherSuite != 0 {
+		enc.AddString("cipher_suite", tls.CipherSuiteName(h.cipherSuite))
+	}
+	if h.serverName != "" {
+		enc.AddString("server_name", h.serverName)
+	}
+	enc.AddString("latency", formatDuration(h.latency))
+	return nil
+}
+
+// TLSHandshake returns the fields for a TLS handshake, e.g. of the tls.ConnectionState of a connection,
+// which took d and failed with err: the server name (SNI) as the tls_server_name label,
+// and the names of the version and the cipher suite, e.g. "TLS 1.3" and "TLS_AES_128_GCM_SHA256",
+// the server name and d as a google.protobuf.Duration as the tls object.
+// The version, the cipher suite and the server name are omitted if they're zero, e.g. for a failed handshake.
+// If err isn't nil, it's logged as the error field and the severity is WARNING.
+func TLSHandshake(version uint16, cipherSuite uint16, serverName string, d time.Duration, err error) []zap.Field {
+	var fields []zap.Field
+	if serverName != "" {
+		fields = append(fields, Label("tls_server_name", serverName))
+	}
+	fields = append(fields, zap.Object("tls", tlsHandshake{version: version, cipherSuite: cipherSuite, serverName: serverName, latency: d}))
+	if err != nil {
+		fields = append(fields, zap.Error(err), Severity(zapcore.WarnLevel))
+	}
+	return fields
+}
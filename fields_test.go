@@ -0,0 +1,785 @@
+package zapcloudlogging
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestAuditEvent(t *testing.T) {
+	tests := []struct {
+		name         string
+		methodName   string
+		resourceName string
+		principal    string
+	}{
+		{
+			name:         "event",
+			methodName:   "SetIamPolicy",
+			resourceName: "projects/my-project/buckets/b",
+			principal:    "user@example.com",
+		},
+		{name: "empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := encodeFields(t, AuditEvent(tt.methodName, tt.resourceName, tt.principal))
+			assertJSON(t, "protoPayload", got["protoPayload"], map[string]any{
+				"@type":              auditLogType,
+				"methodName":         tt.methodName,
+				"resourceName":       tt.resourceName,
+				"authenticationInfo": map[string]any{"principalEmail": tt.principal},
+			})
+		})
+	}
+}
+
+func TestTruncated(t *testing.T) {
+	tests := []struct {
+		name     string
+		v        any
+		maxItems int
+		want     any
+	}{
+		{name: "slice", v: []int{1, 2, 3}, maxItems: 5, want: []int{1, 2, 3}},
+		{name: "truncated slice", v: []int{1, 2, 3, 4, 5}, maxItems: 2, want: []any{1, 2, "...3 more"}},
+		{name: "array", v: [3]string{"a", "b", "c"}, maxItems: 1, want: []any{"a", "...2 more"}},
+		{name: "negative", v: []int{1, 2}, maxItems: -1, want: []any{"...2 more"}},
+		{name: "nil slice", v: []int(nil), maxItems: 1, want: []int{}},
+		{
+			name:     "map",
+			v:        map[string]int{"c": 3, "a": 1, "b": 2},
+			maxItems: 2,
+			want:     map[string]any{"a": 1, "b": 2, "...": "1 more"},
+		},
+		{name: "nil map", v: map[string]int(nil), maxItems: 1, want: nil},
+		{name: "other", v: "value", maxItems: 1, want: "value"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := encodeFields(t, Truncated("items", tt.v, tt.maxItems))
+			assertJSON(t, "items", got["items"], tt.want)
+		})
+	}
+}
+
+func TestValidationErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		errs map[string]string
+		want map[string]any
+	}{
+		{
+			name: "errors",
+			errs: map[string]string{"user.name": "required", "user.age": "must be positive"},
+			want: map[string]any{"user.age": "must be positive", "user.name": "required"},
+		},
+		{name: "empty", errs: map[string]string{}, want: map[string]any{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := encodeFields(t, ValidationErrors(tt.errs), Label("env", "prod"))
+			assertJSON(t, "validationErrors", got["validationErrors"], tt.want)
+			assertJSON(t, "labels", got[LabelsKey], map[string]string{"validation_error": "true", "env": "prod"})
+		})
+	}
+}
+
+// closeRecorder is an io.ReadCloser that records whether it's closed.
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (r *closeRecorder) Close() error {
+	r.closed = true
+	return nil
+}
+
+func TestBody(t *testing.T) {
+	errRead := errors.New("read failed")
+	tests := []struct {
+		name          string
+		r             io.Reader
+		maxBytes      int
+		wantContent   string
+		wantTruncated bool
+		wantRest      string
+		wantErr       error
+	}{
+		{name: "short", r: strings.NewReader("hello"), maxBytes: 10, wantContent: "hello", wantRest: "hello"},
+		{name: "exact", r: strings.NewReader("hello"), maxBytes: 5, wantContent: "hello", wantRest: "hello"},
+		{
+			name:          "truncated",
+			r:             strings.NewReader("hello, world"),
+			maxBytes:      5,
+			wantContent:   "hello",
+			wantTruncated: true,
+			wantRest:      "hello, world",
+		},
+		{
+			name:          "negative",
+			r:             strings.NewReader("hello"),
+			maxBytes:      -1,
+			wantTruncated: true,
+			wantRest:      "hello",
+		},
+		{name: "empty", r: strings.NewReader(""), maxBytes: 5, wantRest: ""},
+		{
+			name:        "error",
+			r:           io.MultiReader(strings.NewReader("he"), errReader{errRead}),
+			maxBytes:    5,
+			wantContent: "he",
+			wantRest:    "he",
+			wantErr:     errRead,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			field, rest := Body("body", tt.r, tt.maxBytes)
+
+			got := encodeFields(t, field)
+			assertJSON(t, "body", got["body"], map[string]any{"content": tt.wantContent, "truncated": tt.wantTruncated})
+
+			b, err := io.ReadAll(rest)
+			if string(b) != tt.wantRest || !errors.Is(err, tt.wantErr) {
+				t.Errorf("ReadAll(rest) = %q, %v, want %q, %v", b, err, tt.wantRest, tt.wantErr)
+			}
+			if _, ok := rest.(io.Closer); ok {
+				t.Errorf("rest implements io.Closer, want not")
+			}
+		})
+	}
+
+	t.Run("closer", func(t *testing.T) {
+		r := &closeRecorder{Reader: strings.NewReader("hello")}
+		_, rest := Body("body", r, 2)
+		c, ok := rest.(io.Closer)
+		if !ok {
+			t.Fatal("rest doesn't implement io.Closer")
+		}
+		if err := c.Close(); err != nil || !r.closed {
+			t.Errorf("Close() = %v, closed = %v, want nil, true", err, r.closed)
+		}
+	})
+}
+
+// payloadObject is a zapcore.ObjectMarshaler logged inline by Payload.
+type payloadObject struct{ id int }
+
+func (o payloadObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddInt("id", o.id)
+	return nil
+}
+
+func TestPayload(t *testing.T) {
+	tests := []struct {
+		name string
+		v    any
+		want map[string]any
+	}{
+		{
+			name: "struct",
+			v: struct {
+				Name  string `json:"name"`
+				Count int    `json:"count"`
+			}{Name: "gopher", Count: 2},
+			want: map[string]any{SeverityKey: "INFO", "count": 2, "name": "gopher"},
+		},
+		{
+			name: "map",
+			v:    map[string]any{"b": []int{1}, "a": map[string]bool{"ok": true}},
+			want: map[string]any{SeverityKey: "INFO", "a": map[string]bool{"ok": true}, "b": []int{1}},
+		},
+		{name: "object marshaler", v: payloadObject{id: 7}, want: map[string]any{SeverityKey: "INFO", "id": 7}},
+		{name: "not an object", v: []string{"a"}, want: map[string]any{SeverityKey: "INFO", "payload": []string{"a"}}},
+		{name: "nil", v: nil, want: map[string]any{SeverityKey: "INFO", "payload": nil}},
+		{name: "unsupported", v: func() {}, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, fields := Payload(tt.v)
+			if msg != "" {
+				t.Errorf("message = %q, want empty", msg)
+			}
+			got := encodeFields(t, fields...)
+			if tt.want == nil {
+				if _, ok := got["payloadError"]; !ok {
+					t.Errorf("entry = %v, want payloadError", got)
+				}
+				return
+			}
+			assertJSON(t, "entry", got, tt.want)
+		})
+	}
+}
+
+func TestRetryAttempt(t *testing.T) {
+	errTimeout := errors.New("timeout")
+	tests := []struct {
+		name        string
+		attempt     int
+		maxAttempts int
+		lastErr     error
+		nextDelay   time.Duration
+		want        map[string]any
+	}{
+		{
+			name:        "first",
+			attempt:     1,
+			maxAttempts: 3,
+			lastErr:     errTimeout,
+			nextDelay:   200 * time.Millisecond,
+			want: map[string]any{
+				SeverityKey: "INFO",
+				"retry":     map[string]any{"attempt": 1, "max_attempts": 3, "remaining": 2, "next_delay": "0.200s"},
+				"error":     "timeout",
+			},
+		},
+		{
+			name:        "last",
+			attempt:     3,
+			maxAttempts: 3,
+			lastErr:     errTimeout,
+			want: map[string]any{
+				SeverityKey: "INFO",
+				"retry":     map[string]any{"attempt": 3, "max_attempts": 3, "remaining": 0, "next_delay": "0s"},
+				"error":     "timeout",
+			},
+		},
+		{
+			name:        "over",
+			attempt:     5,
+			maxAttempts: 3,
+			want: map[string]any{
+				SeverityKey: "INFO",
+				"retry":     map[string]any{"attempt": 5, "max_attempts": 3, "remaining": 0, "next_delay": "0s"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := encodeFields(t, RetryAttempt(tt.attempt, tt.maxAttempts, tt.lastErr, tt.nextDelay)...)
+			assertJSON(t, "entry", got, tt.want)
+		})
+	}
+}
+
+// testState is an enum logged by Enum.
+type testState int
+
+const (
+	stateIdle testState = iota
+	stateRunning
+)
+
+func (s testState) String() string {
+	switch s {
+	case stateIdle:
+		return "idle"
+	case stateRunning:
+		return "running"
+	}
+	return "unknown"
+}
+
+// nilStringer panics in String for a nil pointer.
+type nilStringer struct{ name string }
+
+func (s *nilStringer) String() string { return s.name }
+
+func TestEnum(t *testing.T) {
+	tests := []struct {
+		name string
+		v    fmt.Stringer
+		want any
+	}{
+		{name: "value", v: stateRunning, want: "running"},
+		{name: "zero", v: stateIdle, want: "idle"},
+		{name: "unknown", v: testState(9), want: "unknown"},
+		{name: "nil", v: nil, want: nil},
+		{name: "nil pointer", v: (*nilStringer)(nil), want: "<nil>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := encodeFields(t, Enum("state", tt.v))
+			assertJSON(t, "state", got["state"], tt.want)
+		})
+	}
+}
+
+func TestFeatureFlag(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     any
+		reason    string
+		wantValue any
+		wantLabel string
+	}{
+		{name: "bool", value: true, reason: "TARGETING_MATCH", wantValue: true, wantLabel: "true"},
+		{name: "string", value: "blue", reason: "SPLIT", wantValue: "blue", wantLabel: "blue"},
+		{name: "number", value: 0.25, reason: "DEFAULT", wantValue: 0.25, wantLabel: "0.25"},
+		{name: "no reason", value: 3, wantValue: 3, wantLabel: "3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := encodeFields(t, FeatureFlag("new-checkout", tt.value, tt.reason)...)
+
+			assertJSON(t, "labels", got[LabelsKey], map[string]string{
+				"feature_flag":       "new-checkout",
+				"feature_flag_value": tt.wantLabel,
+			})
+			want := map[string]any{"name": "new-checkout", "value": tt.wantValue}
+			if tt.reason != "" {
+				want["reason"] = tt.reason
+			}
+			assertJSON(t, "feature_flag", got["feature_flag"], want)
+		})
+	}
+}
+
+func TestCache(t *testing.T) {
+	tests := []struct {
+		name string
+		hit  bool
+		d    time.Duration
+		want string
+	}{
+		{name: "hit", hit: true, d: 250 * time.Microsecond, want: "0.000250s"},
+		{name: "miss", d: 12 * time.Millisecond, want: "0.012s"},
+		{name: "instant", hit: true, want: "0s"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := encodeFields(t, Cache("users", tt.hit, tt.d)...)
+			assertJSON(t, "labels", got[LabelsKey], map[string]string{"cache": "users"})
+			assertJSON(t, "cache", got["cache"], map[string]any{"name": "users", "hit": tt.hit, "duration": tt.want})
+		})
+	}
+}
+
+func TestLock(t *testing.T) {
+	tests := []struct {
+		name     string
+		acquired bool
+		waited   time.Duration
+		want     string
+	}{
+		{name: "acquired", acquired: true, waited: 30 * time.Millisecond, want: "0.030s"},
+		{name: "timed out", waited: 10 * time.Second, want: "10s"},
+		{name: "uncontended", acquired: true, want: "0s"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := encodeFields(t, Lock("migrations", tt.acquired, tt.waited)...)
+			assertJSON(t, "labels", got[LabelsKey], map[string]string{"lock": "migrations"})
+			assertJSON(t, "lock", got["lock"], map[string]any{"name": "migrations", "acquired": tt.acquired, "waited": tt.want})
+		})
+	}
+}
+
+func TestJobSummary(t *testing.T) {
+	tests := []struct {
+		name         string
+		processed    int
+		failed       int
+		wantSeverity string
+	}{
+		{name: "succeeded", processed: 120, wantSeverity: "INFO"},
+		{name: "failed", processed: 120, failed: 3, wantSeverity: "ERROR"},
+		{name: "empty", wantSeverity: "INFO"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, sink := newTestLogger(t)
+			logger.Info("job finished", JobSummary("reindex", tt.processed, tt.failed, 90*time.Second)...)
+
+			got := sink.entries(t)[0]
+			assertJSON(t, SeverityKey, got[SeverityKey], tt.wantSeverity)
+			assertJSON(t, "labels", got[LabelsKey], map[string]string{"job": "reindex"})
+			assertJSON(t, "job", got["job"], map[string]any{
+				"name": "reindex", "processed": tt.processed, "failed": tt.failed, "duration": "90s",
+			})
+		})
+	}
+}
+
+func TestPublish(t *testing.T) {
+	tests := []struct {
+		name      string
+		topic     string
+		messageID string
+		d         time.Duration
+		want      string
+	}{
+		{name: "Pub/Sub", topic: "projects/my-project/topics/orders", messageID: "4213", d: 35 * time.Millisecond, want: "0.035s"},
+		{name: "Kafka", topic: "orders", messageID: "orders-0@1024", d: 2 * time.Second, want: "2s"},
+		{name: "no message ID", topic: "orders", want: "0s"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := encodeFields(t, Publish(tt.topic, tt.messageID, tt.d)...)
+			assertJSON(t, "labels", got[LabelsKey], map[string]string{"topic": tt.topic})
+			assertJSON(t, "publish", got["publish"], map[string]any{
+				"topic": tt.topic, "message_id": tt.messageID, "latency": tt.want,
+			})
+		})
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	tests := []struct {
+		name         string
+		from, to     string
+		wantSeverity string
+	}{
+		{name: "opened", from: "closed", to: "open", wantSeverity: "WARNING"},
+		{name: "uppercase", from: "HALF_OPEN", to: "OPEN", wantSeverity: "WARNING"},
+		{name: "half-open", from: "open", to: "half-open", wantSeverity: "INFO"},
+		{name: "closed", from: "half-open", to: "closed", wantSeverity: "INFO"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, sink := newTestLogger(t)
+			logger.Info("circuit breaker changed", CircuitBreaker("payments", tt.from, tt.to)...)
+
+			got := sink.entries(t)[0]
+			assertJSON(t, SeverityKey, got[SeverityKey], tt.wantSeverity)
+			assertJSON(t, "labels", got[LabelsKey], map[string]string{"circuit_breaker": "payments"})
+			assertJSON(t, "circuit_breaker", got["circuit_breaker"], map[string]any{
+				"name": "payments", "from": tt.from, "to": tt.to,
+			})
+		})
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	tests := []struct {
+		name         string
+		allowed      bool
+		remaining    int
+		resetAfter   time.Duration
+		want         string
+		wantSeverity string
+	}{
+		{name: "allowed", allowed: true, remaining: 99, resetAfter: time.Minute, want: "60s", wantSeverity: "INFO"},
+		{name: "last", allowed: true, resetAfter: 1500 * time.Millisecond, want: "1.500s", wantSeverity: "INFO"},
+		{name: "denied", resetAfter: 30 * time.Second, want: "30s", wantSeverity: "WARNING"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, sink := newTestLogger(t)
+			logger.Info("rate limited", RateLimit("client-42", tt.allowed, tt.remaining, tt.resetAfter)...)
+
+			got := sink.entries(t)[0]
+			assertJSON(t, SeverityKey, got[SeverityKey], tt.wantSeverity)
+			assertJSON(t, "labels", got[LabelsKey], map[string]string{"rate_limit_key": "client-42"})
+			assertJSON(t, "rate_limit", got["rate_limit"], map[string]any{
+				"key": "client-42", "allowed": tt.allowed, "remaining": tt.remaining, "reset_after": tt.want,
+			})
+		})
+	}
+}
+
+func TestNetDial(t *testing.T) {
+	tests := []struct {
+		name         string
+		network      string
+		address      string
+		err          error
+		wantSeverity string
+	}{
+		{name: "tcp", network: "tcp", address: "10.0.0.5:5432", wantSeverity: "INFO"},
+		{name: "unix", network: "unix", address: "/run/app.sock", wantSeverity: "INFO"},
+		{name: "failed", network: "tcp", address: "10.0.0.5:5432", err: errors.New("connection refused"), wantSeverity: "WARNING"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, sink := newTestLogger(t)
+			logger.Info("dialed", NetDial(tt.network, tt.address, 3*time.Millisecond, tt.err)...)
+
+			got := sink.entries(t)[0]
+			assertJSON(t, SeverityKey, got[SeverityKey], tt.wantSeverity)
+			assertJSON(t, "labels", got[LabelsKey], map[string]string{"net_address": tt.address})
+			assertJSON(t, "dial", got["dial"], map[string]any{
+				"network": tt.network, "address": tt.address, "latency": "0.003s",
+			})
+			var wantErr any
+			if tt.err != nil {
+				wantErr = tt.err.Error()
+			}
+			assertJSON(t, "error", got["error"], wantErr)
+		})
+	}
+}
+
+func TestWithStack(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields []zap.Field
+		want   map[string]any
+	}{
+		{name: "no fields", want: map[string]any{}},
+		{
+			name:   "fields",
+			fields: []zap.Field{zap.String("state", "draining"), zap.Int("pending", 2)},
+			want:   map[string]any{"state": "draining", "pending": 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields := make([]zap.Field, len(tt.fields), len(tt.fields)+1)
+			copy(fields, tt.fields)
+			logger, sink := newTestLogger(t)
+			logger.Warn("unexpected state", WithStack(fields...)...)
+
+			got := sink.entries(t)[0]
+			for k, v := range tt.want {
+				assertJSON(t, k, got[k], v)
+			}
+			stack, _ := got[StacktraceKey].(string)
+			if !strings.HasPrefix(stack, "github.com/kechako/zapcloudlogging.TestWithStack.func") {
+				t.Errorf("%s = %q, want the stack from the caller of WithStack", StacktraceKey, stack)
+			}
+			if extra := fields[:cap(fields)]; extra[len(extra)-1].Key != "" {
+				t.Errorf("WithStack modified the backing array of fields: %v", extra)
+			}
+		})
+	}
+}
+
+func TestBytes(t *testing.T) {
+	long := []byte(strings.Repeat("a", 1030))
+	tests := []struct {
+		name string
+		b    []byte
+		mode BytesMode
+		want string
+	}{
+		{name: "base64", b: []byte{0xde, 0xad, 0xbe, 0xef}, mode: BytesBase64, want: "3q2+7w=="},
+		{name: "hex", b: []byte{0xde, 0xad, 0xbe, 0xef}, mode: BytesHex, want: "deadbeef"},
+		{name: "utf8", b: []byte("héllo"), mode: BytesUTF8, want: "héllo"},
+		{name: "invalid utf8", b: []byte{'o', 0xff, 0xfe, 'k'}, mode: BytesUTF8, want: "o�k"},
+		{name: "unknown mode", b: []byte{0xde, 0xad, 0xbe, 0xef}, mode: BytesMode(42), want: "3q2+7w=="},
+		{name: "empty", mode: BytesHex, want: ""},
+		{name: "1024 bytes", b: long[:1024], mode: BytesUTF8, want: strings.Repeat("a", 1024)},
+		{name: "truncated", b: long, mode: BytesUTF8, want: strings.Repeat("a", 1024) + "...6 more bytes"},
+		{name: "truncated hex", b: long, mode: BytesHex, want: strings.Repeat("61", 1024) + "...6 more bytes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := encodeFields(t, Bytes("body", tt.b, tt.mode))
+			assertJSON(t, "body", got["body"], tt.want)
+		})
+	}
+}
+
+func TestMigration(t *testing.T) {
+	errDirty := errors.New("dirty database version 4")
+	tests := []struct {
+		name         string
+		fields       []zap.Field
+		wantSeverity string
+		wantVersion  string
+		wantFrom     int
+		wantApplied  []string
+		wantErr      any
+	}{
+		{
+			name:         "migrated",
+			fields:       Migration(3, 5, []string{"004_add_users", "005_add_index"}, 2*time.Second),
+			wantSeverity: "INFO",
+			wantVersion:  "5",
+			wantFrom:     3,
+			wantApplied:  []string{"004_add_users", "005_add_index"},
+		},
+		{
+			name:         "up to date",
+			fields:       Migration(5, 5, nil, 2*time.Second),
+			wantSeverity: "INFO",
+			wantVersion:  "5",
+			wantFrom:     5,
+			wantApplied:  []string{},
+		},
+		{
+			name:         "failed",
+			fields:       MigrationFailed(3, 5, []string{"004_add_users"}, 2*time.Second, errDirty),
+			wantSeverity: "ERROR",
+			wantVersion:  "3",
+			wantFrom:     3,
+			wantApplied:  []string{"004_add_users"},
+			wantErr:      errDirty.Error(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, sink := newTestLogger(t)
+			logger.Warn("migration", tt.fields...)
+
+			got := sink.entries(t)[0]
+			assertJSON(t, SeverityKey, got[SeverityKey], tt.wantSeverity)
+			assertJSON(t, "labels", got[LabelsKey], map[string]string{"schema_version": tt.wantVersion})
+			assertJSON(t, "migration", got["migration"], map[string]any{
+				"from_version": tt.wantFrom,
+				"to_version":   5,
+				"applied":      tt.wantApplied,
+				"duration":     "2s",
+			})
+			assertJSON(t, "error", got["error"], tt.wantErr)
+		})
+	}
+}
+
+func TestWorker(t *testing.T) {
+	tests := []struct {
+		name         string
+		fields       []zap.Field
+		wantSeverity string
+		want         map[string]any
+	}{
+		{
+			name:         "started",
+			fields:       WorkerStarted("indexer"),
+			wantSeverity: "INFO",
+			want:         map[string]any{"name": "indexer", "event": "started"},
+		},
+		{
+			name:         "stopped",
+			fields:       WorkerStopped("indexer", "", time.Hour),
+			wantSeverity: "INFO",
+			want:         map[string]any{"name": "indexer", "event": "stopped", "lifetime": "3600s"},
+		},
+		{
+			name:         "shutdown",
+			fields:       WorkerStopped("indexer", "Shutdown", time.Minute),
+			wantSeverity: "INFO",
+			want:         map[string]any{"name": "indexer", "event": "stopped", "reason": "Shutdown", "lifetime": "60s"},
+		},
+		{
+			name:         "context canceled",
+			fields:       WorkerStopped("indexer", "context canceled", time.Minute),
+			wantSeverity: "INFO",
+			want:         map[string]any{"name": "indexer", "event": "stopped", "reason": "context canceled", "lifetime": "60s"},
+		},
+		{
+			name:         "unexpected",
+			fields:       WorkerStopped("indexer", "queue closed", 1500*time.Millisecond),
+			wantSeverity: "WARNING",
+			want:         map[string]any{"name": "indexer", "event": "stopped", "reason": "queue closed", "lifetime": "1.500s"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, sink := newTestLogger(t)
+			logger.Info("worker", tt.fields...)
+
+			got := sink.entries(t)[0]
+			assertJSON(t, SeverityKey, got[SeverityKey], tt.wantSeverity)
+			assertJSON(t, "labels", got[LabelsKey], map[string]string{"worker": "indexer"})
+			assertJSON(t, "worker", got["worker"], tt.want)
+		})
+	}
+}
+
+func TestConfigReloaded(t *testing.T) {
+	errParse := errors.New("yaml: line 3: did not find expected key")
+	tests := []struct {
+		name         string
+		changed      []string
+		err          error
+		wantSeverity string
+		wantChanged  []string
+		wantErr      any
+	}{
+		{name: "changed", changed: []string{"log.level", "db.pool_size"}, wantSeverity: "INFO", wantChanged: []string{"log.level", "db.pool_size"}},
+		{name: "unchanged", wantSeverity: "INFO", wantChanged: []string{}},
+		{name: "failed", err: errParse, wantSeverity: "ERROR", wantChanged: []string{}, wantErr: errParse.Error()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, sink := newTestLogger(t)
+			logger.Warn("config reloaded", ConfigReloaded("/etc/app/config.yaml", tt.changed, tt.err)...)
+
+			got := sink.entries(t)[0]
+			assertJSON(t, SeverityKey, got[SeverityKey], tt.wantSeverity)
+			assertJSON(t, "labels", got[LabelsKey], map[string]string{"config_source": "/etc/app/config.yaml"})
+			assertJSON(t, "config_reload", got["config_reload"], map[string]any{
+				"source": "/etc/app/config.yaml", "changed": tt.wantChanged,
+			})
+			assertJSON(t, "error", got["error"], tt.wantErr)
+		})
+	}
+}
+
+func TestTLSHandshake(t *testing.T) {
+	errHandshake := errors.New("tls: handshake failure")
+	tests := []struct {
+		name         string
+		version      uint16
+		cipherSuite  uint16
+		serverName   string
+		err          error
+		wantSeverity string
+		wantLabels   any
+		want         map[string]any
+	}{
+		{
+			name:         "TLS 1.3",
+			version:      tls.VersionTLS13,
+			cipherSuite:  tls.TLS_AES_128_GCM_SHA256,
+			serverName:   "api.example.com",
+			wantSeverity: "INFO",
+			wantLabels:   map[string]string{"tls_server_name": "api.example.com"},
+			want: map[string]any{
+				"version": "TLS 1.3", "cipher_suite": "TLS_AES_128_GCM_SHA256",
+				"server_name": "api.example.com", "latency": "0.012s",
+			},
+		},
+		{
+			name:         "no SNI",
+			version:      tls.VersionTLS12,
+			cipherSuite:  tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			wantSeverity: "INFO",
+			want: map[string]any{
+				"version": "TLS 1.2", "cipher_suite": "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", "latency": "0.012s",
+			},
+		},
+		{
+			name:         "unknown version",
+			version:      0x0305,
+			wantSeverity: "INFO",
+			want:         map[string]any{"version": "0x0305", "latency": "0.012s"},
+		},
+		{
+			name:         "failed",
+			serverName:   "api.example.com",
+			err:          errHandshake,
+			wantSeverity: "WARNING",
+			wantLabels:   map[string]string{"tls_server_name": "api.example.com"},
+			want:         map[string]any{"server_name": "api.example.com", "latency": "0.012s"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, sink := newTestLogger(t)
+			logger.Info("handshake", TLSHandshake(tt.version, tt.cipherSuite, tt.serverName, 12*time.Millisecond, tt.err)...)
+
+			got := sink.entries(t)[0]
+			assertJSON(t, SeverityKey, got[SeverityKey], tt.wantSeverity)
+			assertJSON(t, "labels", got[LabelsKey], tt.wantLabels)
+			assertJSON(t, "tls", got["tls"], tt.want)
+			var wantErr any
+			if tt.err != nil {
+				wantErr = tt.err.Error()
+			}
+			assertJSON(t, "error", got["error"], wantErr)
+		})
+	}
+}
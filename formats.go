@@ -0,0 +1,83 @@
+package zapcloudlogging
+
+import (
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// This file provides encoder configs for sinks other than Cloud Logging,
+// for the binaries that ship the same logs to Elastic or Graylog.
+// They map levels like the severity mapping of Cloud Logging.
+
+// ecsLevelEncoder is an encoder for log.level of ECS.
+// It's the lowercase Cloud Logging severity, e.g. "warning" or "critical".
+func ecsLevelEncoder(l zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
+	enc.AppendString(strings.ToLower(SeverityOf(l)))
+}
+
+// NewECSEncoderConfig returns a zapcore.EncoderConfig for the Elastic Common Schema.
+//
+// The ecs.version field is not emitted by the encoder,
+// add it to every entry, e.g. with zap.Fields(zap.String("ecs.version", "1.6.0")).
+//
+// https://www.elastic.co/guide/en/ecs/current/ecs-log.html
+func NewECSEncoderConfig() zapcore.EncoderConfig {
+	return zapcore.EncoderConfig{
+		MessageKey:     "message",
+		LevelKey:       "log.level",
+		TimeKey:        "@timestamp",
+		NameKey:        "log.logger",
+		CallerKey:      "log.origin.file.name",
+		FunctionKey:    "log.origin.function",
+		StacktraceKey:  "error.stack_trace",
+		LineEnding:     zapcore.DefaultLineEnding,
+		EncodeLevel:    ecsLevelEncoder,
+		EncodeTime:     zapcore.ISO8601TimeEncoder,
+		EncodeDuration: zapcore.NanosDurationEncoder,
+		EncodeCaller:   zapcore.ShortCallerEncoder,
+	}
+}
+
+// syslogLevel is the syslog level of each Cloud Logging severity.
+var syslogLevel = map[string]int64{
+	"DEFAULT":   7,
+	"DEBUG":     7,
+	"INFO":      6,
+	"NOTICE":    5,
+	"WARNING":   4,
+	"ERROR":     3,
+	"CRITICAL":  2,
+	"ALERT":     1,
+	"EMERGENCY": 0,
+}
+
+// gelfLevelEncoder is an encoder for level of GELF.
+// It's the syslog level of the Cloud Logging severity.
+func gelfLevelEncoder(l zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
+	enc.AppendInt64(syslogLevel[SeverityOf(l)])
+}
+
+// NewGELFEncoderConfig returns a zapcore.EncoderConfig for the Graylog Extended Log Format.
+//
+// The version and host fields are required by GELF but not emitted by the encoder,
+// add them to every entry, e.g. with zap.Fields(zap.String("version", "1.1"), zap.String("host", host)).
+// Other fields must be prefixed with an underscore to be additional fields.
+//
+// https://go2docs.graylog.org/current/getting_in_log_data/gelf.html
+func NewGELFEncoderConfig() zapcore.EncoderConfig {
+	return zapcore.EncoderConfig{
+		MessageKey:     "short_message",
+		LevelKey:       "level",
+		TimeKey:        "timestamp",
+		NameKey:        "_logger",
+		CallerKey:      "_caller",
+		FunctionKey:    zapcore.OmitKey,
+		StacktraceKey:  "full_message",
+		LineEnding:     zapcore.DefaultLineEnding,
+		EncodeLevel:    gelfLevelEncoder,
+		EncodeTime:     zapcore.EpochTimeEncoder,
+		EncodeDuration: zapcore.MillisDurationEncoder,
+		EncodeCaller:   zapcore.ShortCallerEncoder,
+	}
+}
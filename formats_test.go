@@ -0,0 +1,77 @@
+package zapcloudlogging
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestFormatLevels(t *testing.T) {
+	tests := []struct {
+		level    zapcore.Level
+		wantECS  string
+		wantGELF int
+	}{
+		{level: zapcore.DebugLevel, wantECS: "debug", wantGELF: 7},
+		{level: zapcore.InfoLevel, wantECS: "info", wantGELF: 6},
+		{level: zapcore.WarnLevel, wantECS: "warning", wantGELF: 4},
+		{level: zapcore.ErrorLevel, wantECS: "error", wantGELF: 3},
+		{level: zapcore.DPanicLevel, wantECS: "critical", wantGELF: 2},
+		{level: zapcore.PanicLevel, wantECS: "alert", wantGELF: 1},
+		{level: zapcore.FatalLevel, wantECS: "emergency", wantGELF: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.level.String(), func(t *testing.T) {
+			ent := zapcore.Entry{Level: tt.level, Message: "msg"}
+			ecs := encodeEntry(t, NewECSEncoderConfig(), ent)
+			assertJSON(t, "log.level", ecs["log.level"], tt.wantECS)
+			gelf := encodeEntry(t, NewGELFEncoderConfig(), ent)
+			assertJSON(t, "level", gelf["level"], tt.wantGELF)
+		})
+	}
+}
+
+func TestFormatEntries(t *testing.T) {
+	ent := zapcore.Entry{
+		Level:      zapcore.InfoLevel,
+		Time:       time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		LoggerName: "db",
+		Message:    "msg",
+		Caller:     testCaller,
+	}
+	tests := []struct {
+		name string
+		cfg  zapcore.EncoderConfig
+		want map[string]any
+	}{
+		{
+			name: "ECS",
+			cfg:  NewECSEncoderConfig(),
+			want: map[string]any{
+				"@timestamp":           "2024-01-02T03:04:05.000Z",
+				"log.level":            "info",
+				"log.logger":           "db",
+				"log.origin.file.name": testCaller.TrimmedPath(),
+				"log.origin.function":  testCaller.Function,
+				"message":              "msg",
+			},
+		},
+		{
+			name: "GELF",
+			cfg:  NewGELFEncoderConfig(),
+			want: map[string]any{
+				"_caller":       testCaller.TrimmedPath(),
+				"_logger":       "db",
+				"level":         6,
+				"short_message": "msg",
+				"timestamp":     1704164645,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertJSON(t, "entry", encodeEntry(t, tt.cfg, ent), tt.want)
+		})
+	}
+}
@@ -2,9 +2,11 @@ module github.com/kechako/zapcloudlogging
 
 go 1.18
 
-require go.uber.org/zap v1.21.0
-
 require (
-	go.uber.org/atomic v1.9.0 // indirect
-	go.uber.org/multierr v1.7.0 // indirect
+	go.opentelemetry.io/otel v1.11.2
+	go.uber.org/multierr v1.7.0
+	go.uber.org/zap v1.23.0
+	google.golang.org/protobuf v1.28.1
 )
+
+require go.uber.org/atomic v1.9.0 // indirect
@@ -0,0 +1,58 @@
+package zapcloudlogging
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// goroutineFields holds the fields set by SetContext, keyed by goroutine ID.
+var goroutineFields sync.Map
+
+// SetContext sets fields, typically the trace fields, that a core created by
+// NewGoroutineContextCore adds to every entry logged by the current goroutine.
+// It replaces the fields set before.
+func SetContext(fields ...zap.Field) {
+	goroutineFields.Store(goroutineID(), fields)
+}
+
+// ClearContext clears the fields set by SetContext for the current goroutine.
+func ClearContext() {
+	goroutineFields.Delete(goroutineID())
+}
+
+// NewGoroutineContextCore returns a zapcore.Core that adds the fields set by SetContext
+// to the entries logged by the same goroutine.
+//
+// This is a workaround for code that can't pass a context.Context around.
+// Go has no goroutine-local storage, so the goroutine is identified by parsing runtime.Stack on every entry.
+// The fields are not inherited by goroutines started by the goroutine,
+// and they must be cleared with ClearContext before the goroutine is reused, e.g. by a worker pool,
+// or exits, otherwise they leak.
+func NewGoroutineContextCore(core zapcore.Core) zapcore.Core {
+	return WithTraceCore(core, func(zapcore.Entry) []zapcore.Field {
+		if fields, ok := goroutineFields.Load(goroutineID()); ok {
+			return fields.([]zapcore.Field)
+		}
+		return nil
+	})
+}
+
+var goroutinePrefix = []byte("goroutine ")
+
+// goroutineID returns the ID of the current goroutine.
+func goroutineID() uint64 {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	// The stack starts with "goroutine 123 [running]:".
+	b = bytes.TrimPrefix(b, goroutinePrefix)
+	if i := bytes.IndexByte(b, ' '); i >= 0 {
+		b = b[:i]
+	}
+	id, _ := strconv.ParseUint(string(b), 10, 64)
+	return id
+}
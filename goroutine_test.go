@@ -0,0 +1,77 @@
+package zapcloudlogging
+
+import (
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestNewGoroutineContextCore(t *testing.T) {
+	tests := []struct {
+		name string
+		log  func(logger *zap.Logger)
+		want []map[string]any
+	}{
+		{
+			name: "no context",
+			log:  func(logger *zap.Logger) { logger.Info("msg") },
+			want: []map[string]any{{SeverityKey: "INFO", MessageKey: "msg"}},
+		},
+		{
+			name: "set",
+			log: func(logger *zap.Logger) {
+				SetContext(zap.String("request_id", "r1"))
+				defer ClearContext()
+				logger.Info("msg", zap.Int("n", 1))
+			},
+			want: []map[string]any{{SeverityKey: "INFO", MessageKey: "msg", "request_id": "r1", "n": 1}},
+		},
+		{
+			name: "replaced",
+			log: func(logger *zap.Logger) {
+				SetContext(zap.String("request_id", "r1"))
+				SetContext(zap.String("request_id", "r2"))
+				defer ClearContext()
+				logger.Info("msg")
+			},
+			want: []map[string]any{{SeverityKey: "INFO", MessageKey: "msg", "request_id": "r2"}},
+		},
+		{
+			name: "cleared",
+			log: func(logger *zap.Logger) {
+				SetContext(zap.String("request_id", "r1"))
+				logger.Info("first")
+				ClearContext()
+				logger.Info("second")
+			},
+			want: []map[string]any{
+				{SeverityKey: "INFO", MessageKey: "first", "request_id": "r1"},
+				{SeverityKey: "INFO", MessageKey: "second"},
+			},
+		},
+		{
+			name: "other goroutine",
+			log: func(logger *zap.Logger) {
+				SetContext(zap.String("request_id", "r1"))
+				defer ClearContext()
+				done := make(chan struct{})
+				go func() {
+					defer close(done)
+					logger.Info("msg")
+				}()
+				<-done
+			},
+			want: []map[string]any{{SeverityKey: "INFO", MessageKey: "msg"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, sink := newTestCore(t)
+			logger := zap.New(WrapCore(NewGoroutineContextCore(core)))
+
+			tt.log(logger)
+
+			assertJSON(t, "entries", sink.entries(t), tt.want)
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package zapcloudlogging
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// A GRPCCode is a status code of gRPC, i.e. codes.Code of google.golang.org/grpc/codes,
+// so that this package doesn't depend on gRPC.
+type GRPCCode interface {
+	~uint32
+	String() string
+}
+
+// grpcCodeLevels are the levels of the gRPC status codes, indexed by the codes.
+// Like the interceptors of go-grpc-middleware, server errors are logged at ERROR
+// and errors to be retried or fixed by the client at WARNING.
+var grpcCodeLevels = [...]zapcore.Level{
+	0:  zapcore.InfoLevel,  // OK
+	1:  zapcore.InfoLevel,  // Canceled
+	2:  zapcore.ErrorLevel, // Unknown
+	3:  zapcore.InfoLevel,  // InvalidArgument
+	4:  zapcore.WarnLevel,  // DeadlineExceeded
+	5:  zapcore.InfoLevel,  // NotFound
+	6:  zapcore.InfoLevel,  // AlreadyExists
+	7:  zapcore.WarnLevel,  // PermissionDenied
+	8:  zapcore.WarnLevel,  // ResourceExhausted
+	9:  zapcore.WarnLevel,  // FailedPrecondition
+	10: zapcore.WarnLevel,  // Aborted
+	11: zapcore.WarnLevel,  // OutOfRange
+	12: zapcore.ErrorLevel, // Unimplemented
+	13: zapcore.ErrorLevel, // Internal
+	14: zapcore.WarnLevel,  // Unavailable
+	15: zapcore.ErrorLevel, // DataLoss
+	16: zapcore.InfoLevel,  // Unauthenticated
+}
+
+// grpcCodeLevel returns the level of a gRPC status code. Unknown codes are logged at ERROR.
+func grpcCodeLevel(code uint32) zapcore.Level {
+	if int(code) < len(grpcCodeLevels) {
+		return grpcCodeLevels[code]
+	}
+	return zapcore.ErrorLevel
+}
+
+type grpcCall struct {
+	method  string
+	peer    string
+	code    string
+	latency time.Duration
+}
+
+func (c grpcCall) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("method", c.method)
+	if c.peer != "" {
+		enc.AddString("peer", c.peer)
+	}
+	enc.AddString("code", c.code)
+	enc.AddString("latency", FormatLatency(c.latency))
+	return nil
+}
+
+// GRPCCall returns the fields for a gRPC call of fullMethod, e.g. "/pkg.Service/Method",
+// with peer and the status code completed in d: the method as the grpc_method label,
+// and the method, the peer, the name of the code and the latency as a google.protobuf.Duration as the grpc object.
+//
+// The severity is overridden by the code: codes caused by the server, e.g. Internal, at ERROR,
+// codes to be retried or fixed by the client, e.g. Unavailable, at WARNING, and the others at INFO.
+func GRPCCall[C GRPCCode](fullMethod, peer string, code C, d time.Duration) []zap.Field {
+	return []zap.Field{
+		Label("grpc_method", fullMethod),
+		zap.Object("grpc", grpcCall{
+			method:  fullMethod,
+			peer:    peer,
+			code:    code.String(),
+			latency: d,
+		}),
+		Severity(grpcCodeLevel(uint32(code))),
+	}
+}
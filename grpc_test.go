@@ -0,0 +1,50 @@
+package zapcloudlogging
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// testCode is a gRPC status code like codes.Code.
+type testCode uint32
+
+func (c testCode) String() string {
+	names := []string{"OK", "Canceled", "Unknown", "InvalidArgument", "DeadlineExceeded"}
+	if int(c) < len(names) {
+		return names[c]
+	}
+	return fmt.Sprintf("Code(%d)", uint32(c))
+}
+
+func TestGRPCCall(t *testing.T) {
+	tests := []struct {
+		name         string
+		peer         string
+		code         testCode
+		wantSeverity string
+	}{
+		{name: "OK", peer: "10.0.0.1:51234", code: 0, wantSeverity: "INFO"},
+		{name: "no peer", code: 1, wantSeverity: "INFO"},
+		{name: "Unknown", code: 2, wantSeverity: "ERROR"},
+		{name: "DeadlineExceeded", code: 4, wantSeverity: "WARNING"},
+		{name: "Unavailable", code: 14, wantSeverity: "WARNING"},
+		{name: "Internal", code: 13, wantSeverity: "ERROR"},
+		{name: "out of range", code: 42, wantSeverity: "ERROR"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, sink := newTestLogger(t)
+			logger.Info("call", GRPCCall("/pkg.Service/Method", tt.peer, tt.code, 1500*time.Millisecond)...)
+
+			got := sink.entries(t)[0]
+			assertJSON(t, SeverityKey, got[SeverityKey], tt.wantSeverity)
+			assertJSON(t, "labels", got[LabelsKey], map[string]string{"grpc_method": "/pkg.Service/Method"})
+			want := map[string]any{"method": "/pkg.Service/Method", "code": tt.code.String(), "latency": "1.500s"}
+			if tt.peer != "" {
+				want["peer"] = tt.peer
+			}
+			assertJSON(t, "grpc", got["grpc"], want)
+		})
+	}
+}
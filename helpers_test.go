@@ -0,0 +1,169 @@
+package zapcloudlogging
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// testSinkScheme is the URL scheme of the sinks of the loggers built by buildTestLogger.
+const testSinkScheme = "zcltest"
+
+var (
+	testSinks    sync.Map
+	testSinkSeq  uint64
+	registerSink sync.Once
+)
+
+// testSink is a zap.Sink that buffers the entries written to it.
+type testSink struct {
+	// discard discards the entries, e.g. for benchmarks.
+	discard bool
+
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (s *testSink) Write(p []byte) (int, error) {
+	if s.discard {
+		return len(p), nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.buf.Write(p)
+}
+
+func (s *testSink) Sync() error  { return nil }
+func (s *testSink) Close() error { return nil }
+
+// String returns the written entries.
+func (s *testSink) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.buf.String()
+}
+
+// entries returns the written entries decoded from JSON.
+func (s *testSink) entries(t testing.TB) []map[string]any {
+	t.Helper()
+	return decodeEntries(t, s.String())
+}
+
+// decodeEntries decodes the JSON entries of out, one per line.
+func decodeEntries(t testing.TB, out string) []map[string]any {
+	t.Helper()
+	var entries []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		if line == "" {
+			continue
+		}
+		var e map[string]any
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("invalid JSON entry %q: %v", line, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// buildTestLogger builds a logger from cfg with opts like NewProduction and NewDevelopment,
+// writing to the returned sink, without the timestamp and the caller unless opts set them.
+func buildTestLogger(t testing.TB, cfg zap.Config, newEncoderConfig func(...EncoderOption) zapcore.EncoderConfig, opts ...Option) (*zap.Logger, *testSink) {
+	t.Helper()
+	path, sink := newTestSinkPath(t)
+	cfg.OutputPaths = []string{path}
+	opts = append([]Option{WithConfig(func(cfg *zap.Config) {
+		cfg.EncoderConfig.TimeKey = zapcore.OmitKey
+		cfg.EncoderConfig.CallerKey = zapcore.OmitKey
+	})}, opts...)
+	logger, err := build(cfg, newEncoderConfig, opts)
+	if err != nil {
+		t.Fatalf("build() = %v", err)
+	}
+	return logger, sink
+}
+
+// newTestSinkPath returns the output path of a new sink for zap.Config.OutputPaths, and the sink.
+func newTestSinkPath(t testing.TB) (string, *testSink) {
+	registerSink.Do(func() {
+		err := zap.RegisterSink(testSinkScheme, func(u *url.URL) (zap.Sink, error) {
+			s, _ := testSinks.Load(u.Opaque)
+			return s.(*testSink), nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	name := strconv.FormatUint(atomic.AddUint64(&testSinkSeq, 1), 10)
+	sink := &testSink{}
+	testSinks.Store(name, sink)
+	t.Cleanup(func() { testSinks.Delete(name) })
+	return testSinkScheme + ":" + name, sink
+}
+
+// newTestLogger builds a logger like NewProduction with opts, see buildTestLogger.
+func newTestLogger(t testing.TB, opts ...Option) (*zap.Logger, *testSink) {
+	t.Helper()
+	return buildTestLogger(t, NewProductionConfig(), NewProductionEncoderConfig, opts...)
+}
+
+// newTestCore returns a core writing the entries at DEBUG or above to the returned sink
+// with the encoding of this package, without the timestamp and the caller.
+func newTestCore(t testing.TB) (zapcore.Core, *testSink) {
+	t.Helper()
+	cfg := NewProductionEncoderConfig()
+	cfg.TimeKey = zapcore.OmitKey
+	cfg.CallerKey = zapcore.OmitKey
+	enc, err := newEncoder(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink := &testSink{}
+	return zapcore.NewCore(enc, sink, zapcore.DebugLevel), sink
+}
+
+// encodeFields returns fields encoded as a JSON object with the encoding of this package.
+func encodeFields(t testing.TB, fields ...zap.Field) map[string]any {
+	t.Helper()
+	core, sink := newTestCore(t)
+	zap.New(WrapCore(core)).Info("", fields...)
+	entries := sink.entries(t)
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(entries))
+	}
+	return entries[0]
+}
+
+// jsonOf returns v encoded in JSON, to compare decoded values.
+func jsonOf(t testing.TB, v any) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+// assertJSON reports an error if got and want aren't encoded in the same JSON.
+func assertJSON(t testing.TB, name string, got, want any) {
+	t.Helper()
+	if g, w := jsonOf(t, got), jsonOf(t, want); g != w {
+		t.Errorf("%s = %s, want %s", name, g, w)
+	}
+}
+
+func assertStrings(t testing.TB, got, want []string) {
+	t.Helper()
+	if strings.Join(got, "\x00") != strings.Join(want, "\x00") || len(got) != len(want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,199 @@
+package zapcloudlogging
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// lazyProjectID returns a function that returns the project ID detected with DetectProjectID on the first call.
+// If the detection fails, the project ID is empty.
+func lazyProjectID() func() string {
+	var (
+		once      sync.Once
+		projectID string
+	)
+	return func() string {
+		once.Do(func() {
+			projectID, _ = DetectProjectID(context.Background())
+		})
+		return projectID
+	}
+}
+
+const reportedErrorEventType = "type.googleapis.com/google.devtools.clouderrorreporting.v1beta1.ReportedErrorEvent"
+
+// errorContext is the context of an error reported to Error Reporting.
+//
+// https://cloud.google.com/error-reporting/reference/rest/v1beta1/ErrorContext
+type errorContext struct {
+	r      *http.Request
+	status int
+}
+
+func (c errorContext) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	return enc.AddObject("httpRequest", zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+		enc.AddString("method", c.r.Method)
+		enc.AddString("url", c.r.URL.String())
+		enc.AddString("userAgent", c.r.UserAgent())
+		enc.AddString("referrer", c.r.Referer())
+		enc.AddInt("responseStatusCode", c.status)
+		enc.AddString("remoteIp", c.r.RemoteAddr)
+		return nil
+	}))
+}
+
+// Recoverer returns a middleware that recovers panics in the handler, logs them with logger and responds 500.
+//
+// The entry is logged at CRITICAL with the stack trace of the panic and the trace of the request,
+// in the format of Error Reporting, so that panics are grouped there automatically.
+// The project ID for the trace is detected with DetectProjectID when the first panic is logged.
+// The severity is overridden with Severity, so logger must be wrapped by WrapCore, e.g. built by NewProduction,
+// otherwise the entry is logged at ERROR.
+//
+// https://cloud.google.com/error-reporting/docs/formatting-error-messages
+func Recoverer(logger *zap.Logger) func(http.Handler) http.Handler {
+	// The stack trace of the panic is logged instead.
+	logger = logger.WithOptions(zap.AddStacktrace(zap.LevelEnablerFunc(func(zapcore.Level) bool { return false })))
+
+	projectID := lazyProjectID()
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				v := recover()
+				if v == nil {
+					return
+				}
+				if v == http.ErrAbortHandler {
+					// Aborting the handler is not an error.
+					panic(v)
+				}
+
+				msg := fmt.Sprintf("panic: %v", v)
+				stack := msg + "\n\n" + string(debug.Stack())
+				fields := append(TraceContextFromHeader(r.Header, projectID()),
+					zap.String("@type", reportedErrorEventType),
+					zap.String("stack_trace", stack),
+					zap.Object("context", errorContext{r: r, status: http.StatusInternalServerError}),
+					Severity(zapcore.DPanicLevel),
+				)
+				logger.Error(msg, fields...)
+
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+type middlewareOptions struct {
+	traceHeader  string
+	requireSpan  bool
+	headerLabels []string
+}
+
+// A MiddlewareOption configures the middleware returned by Middleware.
+type MiddlewareOption func(*middlewareOptions)
+
+// WithTraceHeader sets the name of the header the trace context is read from,
+// for proxies that rename the header. The value must be in the format of X-Cloud-Trace-Context.
+// By default, the X-Cloud-Trace-Context header is read.
+func WithTraceHeader(name string) MiddlewareOption {
+	return func(o *middlewareOptions) {
+		o.traceHeader = name
+	}
+}
+
+// WithRequireSpan makes the logger correlated with the trace only when the trace context has a span ID,
+// so that entries never have a trace without a span. The trace context is still forwarded.
+// By default, the logger has the trace fields of whatever the trace context has.
+func WithRequireSpan() MiddlewareOption {
+	return func(o *middlewareOptions) {
+		o.requireSpan = true
+	}
+}
+
+// sensitiveHeaders are the headers never set as labels by WithHeaderLabels, since they carry credentials.
+var sensitiveHeaders = map[string]struct{}{
+	"Authorization":       {},
+	"Proxy-Authorization": {},
+	"Cookie":              {},
+	"Set-Cookie":          {},
+	"X-Api-Key":           {},
+	"X-Csrf-Token":        {},
+}
+
+// WithHeaderLabels sets the values of the request headers named names, e.g. X-Tenant-ID, as labels of the logger,
+// so that the entries can be filtered by them. The label keys are the names lowercased,
+// with characters other than letters, digits, underscores and hyphens replaced with underscores, e.g. "x-tenant-id".
+// Multiple values of a header are joined with commas, and absent headers are skipped.
+// Headers carrying credentials, such as Authorization and Cookie, are always skipped.
+func WithHeaderLabels(names ...string) MiddlewareOption {
+	return func(o *middlewareOptions) {
+		for _, name := range names {
+			name = http.CanonicalHeaderKey(name)
+			if _, ok := sensitiveHeaders[name]; ok {
+				continue
+			}
+			o.headerLabels = append(o.headerLabels, name)
+		}
+	}
+}
+
+// headerLabels returns the labels for the headers names in h.
+func headerLabels(h http.Header, names []string) labels {
+	var ls labels
+	for _, name := range names {
+		if vs := h.Values(name); len(vs) > 0 {
+			ls = append(ls, label{key: labelKey(name), value: strings.Join(vs, ",")})
+		}
+	}
+	return ls
+}
+
+// Middleware returns a middleware that associates the request with its trace.
+//
+// The X-Cloud-Trace-Context header of the request, or the header set by WithTraceHeader, is stored in the request context,
+// so that it's forwarded by the transport returned by NewLoggingTransport,
+// and logger with the trace fields of the request is stored with NewContext,
+// so that the handler logs with FromContext(r.Context()).
+// The project ID for the trace is detected with DetectProjectID when the first request with a trace is served.
+//
+// The correlation ID of the request, read from the X-Correlation-ID header or ensured by EnsureCorrelationID,
+// is also stored in the request context and set as the correlation_id label of the logger.
+func Middleware(logger *zap.Logger, opts ...MiddlewareOption) func(http.Handler) http.Handler {
+	o := middlewareOptions{traceHeader: CloudTraceContextHeader}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	projectID := lazyProjectID()
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			l := logger
+			if tc, ok := parseCloudTraceContext(r.Header.Get(o.traceHeader)); ok {
+				ctx = withTraceContext(ctx, tc)
+				if tc.spanID != "" || !o.requireSpan {
+					l = l.With(tc.fields(projectID())...)
+				}
+			}
+			if id := r.Header.Get(CorrelationIDHeader); id != "" && len(id) <= maxCorrelationIDLen {
+				ctx = withCorrelationID(ctx, id)
+			}
+			ctx, id := EnsureCorrelationID(ctx)
+			l = l.With(Label("correlation_id", id))
+			if ls := headerLabels(r.Header, o.headerLabels); len(ls) > 0 {
+				l = l.With(zap.Object(LabelsKey, ls))
+			}
+			next.ServeHTTP(w, r.WithContext(NewContext(ctx, l)))
+		})
+	}
+}
@@ -0,0 +1,288 @@
+package zapcloudlogging
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRecoverer(t *testing.T) {
+	tests := []struct {
+		name       string
+		header     http.Header
+		handler    http.HandlerFunc
+		wantStatus int
+		want       map[string]any
+	}{
+		{
+			name:       "no panic",
+			handler:    func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) },
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name:       "panic",
+			handler:    func(w http.ResponseWriter, r *http.Request) { panic("boom") },
+			wantStatus: http.StatusInternalServerError,
+			want: map[string]any{
+				SeverityKey: "CRITICAL",
+				MessageKey:  "panic: boom",
+				"@type":     reportedErrorEventType,
+				"context": map[string]any{"httpRequest": map[string]any{
+					"method":             "GET",
+					"url":                "/items?id=1",
+					"userAgent":          "test",
+					"referrer":           "",
+					"responseStatusCode": 500,
+					"remoteIp":           "192.0.2.1:1234",
+				}},
+			},
+		},
+		{
+			name:       "panic with trace",
+			header:     http.Header{CloudTraceContextHeader: {testTraceID + "/74;o=1"}},
+			handler:    func(w http.ResponseWriter, r *http.Request) { panic(http.ErrBodyNotAllowed) },
+			wantStatus: http.StatusInternalServerError,
+			want: map[string]any{
+				SeverityKey:     "CRITICAL",
+				MessageKey:      "panic: " + http.ErrBodyNotAllowed.Error(),
+				"@type":         reportedErrorEventType,
+				TraceKey:        "projects/my-project/traces/" + testTraceID,
+				SpanIDKey:       testSpanID,
+				TraceSampledKey: true,
+				"context": map[string]any{"httpRequest": map[string]any{
+					"method":             "GET",
+					"url":                "/items?id=1",
+					"userAgent":          "test",
+					"referrer":           "",
+					"responseStatusCode": 500,
+					"remoteIp":           "192.0.2.1:1234",
+				}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GOOGLE_CLOUD_PROJECT", "my-project")
+			logger, sink := newTestLogger(t)
+
+			r := httptest.NewRequest(http.MethodGet, "/items?id=1", nil)
+			r.RemoteAddr = "192.0.2.1:1234"
+			r.Header.Set("User-Agent", "test")
+			for k, vs := range tt.header {
+				r.Header[k] = vs
+			}
+			w := httptest.NewRecorder()
+			Recoverer(logger)(tt.handler).ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			entries := sink.entries(t)
+			if tt.want == nil {
+				if len(entries) != 0 {
+					t.Errorf("got %d entries, want 0", len(entries))
+				}
+				return
+			}
+			if len(entries) != 1 {
+				t.Fatalf("got %d entries, want 1", len(entries))
+			}
+			e := entries[0]
+			stack, _ := e["stack_trace"].(string)
+			if !strings.HasPrefix(stack, tt.want[MessageKey].(string)+"\n\ngoroutine ") {
+				t.Errorf("stack_trace = %q, want the message and the stack", stack)
+			}
+			if _, ok := e[StacktraceKey]; ok {
+				t.Errorf("entry has %s, want only stack_trace", StacktraceKey)
+			}
+			delete(e, "stack_trace")
+			assertJSON(t, "entry", e, tt.want)
+		})
+	}
+
+	t.Run("abort handler", func(t *testing.T) {
+		logger, sink := newTestLogger(t)
+		defer func() {
+			if v := recover(); v != http.ErrAbortHandler {
+				t.Errorf("recovered %v, want http.ErrAbortHandler", v)
+			}
+			if n := len(sink.entries(t)); n != 0 {
+				t.Errorf("got %d entries, want 0", n)
+			}
+		}()
+		Recoverer(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic(http.ErrAbortHandler)
+		})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+}
+
+// serveMiddleware serves a request with header through Middleware with opts,
+// and returns the entry logged by the handler with the logger of the request context.
+func serveMiddleware(t *testing.T, header http.Header, opts ...MiddlewareOption) map[string]any {
+	t.Helper()
+	t.Setenv("GOOGLE_CLOUD_PROJECT", "my-project")
+	logger, sink := newTestLogger(t)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	for k, vs := range header {
+		r.Header[http.CanonicalHeaderKey(k)] = vs
+	}
+	Middleware(logger, opts...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		FromContext(r.Context()).Info("msg")
+	})).ServeHTTP(httptest.NewRecorder(), r)
+
+	entries := sink.entries(t)
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(entries))
+	}
+	return entries[0]
+}
+
+func TestMiddlewareTraceHeader(t *testing.T) {
+	traceFields := map[string]any{
+		SeverityKey:     "INFO",
+		MessageKey:      "msg",
+		TraceKey:        "projects/my-project/traces/" + testTraceID,
+		SpanIDKey:       testSpanID,
+		TraceSampledKey: true,
+		LabelsKey:       map[string]any{"correlation_id": testTraceID},
+	}
+	noTrace := map[string]any{
+		SeverityKey: "INFO",
+		MessageKey:  "msg",
+		LabelsKey:   map[string]any{"correlation_id": "c1"},
+	}
+	tests := []struct {
+		name   string
+		opts   []MiddlewareOption
+		header http.Header
+		want   map[string]any
+	}{
+		{
+			name:   "default",
+			header: http.Header{CloudTraceContextHeader: {testTraceID + "/74;o=1"}},
+			want:   traceFields,
+		},
+		{
+			name:   "no trace",
+			header: http.Header{CorrelationIDHeader: {"c1"}},
+			want:   noTrace,
+		},
+		{
+			name:   "renamed",
+			opts:   []MiddlewareOption{WithTraceHeader("X-Trace")},
+			header: http.Header{"X-Trace": {testTraceID + "/74;o=1"}},
+			want:   traceFields,
+		},
+		{
+			name: "default ignored",
+			opts: []MiddlewareOption{WithTraceHeader("X-Trace")},
+			header: http.Header{
+				CloudTraceContextHeader: {testTraceID + "/74;o=1"},
+				CorrelationIDHeader:     {"c1"},
+			},
+			want: noTrace,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertJSON(t, "entry", serveMiddleware(t, tt.header, tt.opts...), tt.want)
+		})
+	}
+}
+
+func TestWithRequireSpan(t *testing.T) {
+	withSpan := map[string]any{
+		SeverityKey:     "INFO",
+		MessageKey:      "msg",
+		TraceKey:        "projects/my-project/traces/" + testTraceID,
+		SpanIDKey:       testSpanID,
+		TraceSampledKey: true,
+		LabelsKey:       map[string]any{"correlation_id": testTraceID},
+	}
+	withoutSpan := map[string]any{
+		SeverityKey:     "INFO",
+		MessageKey:      "msg",
+		TraceKey:        "projects/my-project/traces/" + testTraceID,
+		TraceSampledKey: false,
+		LabelsKey:       map[string]any{"correlation_id": testTraceID},
+	}
+	uncorrelated := map[string]any{
+		SeverityKey: "INFO",
+		MessageKey:  "msg",
+		LabelsKey:   map[string]any{"correlation_id": testTraceID},
+	}
+	tests := []struct {
+		name   string
+		opts   []MiddlewareOption
+		header string
+		want   map[string]any
+	}{
+		{name: "span", header: testTraceID + "/74;o=1", want: withSpan},
+		{name: "no span", header: testTraceID, want: withoutSpan},
+		{name: "required span", opts: []MiddlewareOption{WithRequireSpan()}, header: testTraceID + "/74;o=1", want: withSpan},
+		{name: "required no span", opts: []MiddlewareOption{WithRequireSpan()}, header: testTraceID, want: uncorrelated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{CloudTraceContextHeader: {tt.header}}
+			assertJSON(t, "entry", serveMiddleware(t, header, tt.opts...), tt.want)
+		})
+	}
+}
+
+func TestWithHeaderLabels(t *testing.T) {
+	tests := []struct {
+		name   string
+		names  []string
+		header http.Header
+		want   map[string]any
+	}{
+		{
+			name:   "header",
+			names:  []string{"X-Tenant-ID"},
+			header: http.Header{"X-Tenant-Id": {"acme"}},
+			want:   map[string]any{"x-tenant-id": "acme"},
+		},
+		{
+			name:   "case-insensitive name",
+			names:  []string{"x-tenant-id"},
+			header: http.Header{"X-Tenant-Id": {"acme"}},
+			want:   map[string]any{"x-tenant-id": "acme"},
+		},
+		{
+			name:   "multiple values",
+			names:  []string{"X-Region"},
+			header: http.Header{"X-Region": {"asia", "us"}},
+			want:   map[string]any{"x-region": "asia,us"},
+		},
+		{
+			name:   "sanitized key",
+			names:  []string{"X-Client.Version"},
+			header: http.Header{"X-Client.version": {"1.2"}},
+			want:   map[string]any{"x-client_version": "1.2"},
+		},
+		{name: "absent", names: []string{"X-Tenant-ID"}},
+		{
+			name:   "sensitive",
+			names:  []string{"Authorization", "cookie", "X-API-Key"},
+			header: http.Header{"Authorization": {"Bearer t"}, "Cookie": {"s=1"}, "X-Api-Key": {"k"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := map[string]any{"correlation_id": "c1"}
+			for k, v := range tt.want {
+				want[k] = v
+			}
+			header := http.Header{CorrelationIDHeader: {"c1"}}
+			for k, vs := range tt.header {
+				header[k] = vs
+			}
+
+			got := serveMiddleware(t, header, WithHeaderLabels(tt.names...))
+			assertJSON(t, "labels", got[LabelsKey], want)
+		})
+	}
+}
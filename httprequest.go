@@ -0,0 +1,105 @@
+package zapcloudlogging
+
+import (
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// HTTPRequest is the HTTP request of an entry, shown in the Logs Explorer with the method, status and latency.
+// Zero fields are omitted.
+//
+// https://cloud.google.com/logging/docs/reference/v2/rest/v2/LogEntry#httprequest
+type HTTPRequest struct {
+	RequestMethod string
+	RequestURL    string
+	RequestSize   int64
+	Status        int
+	ResponseSize  int64
+	UserAgent     string
+	RemoteIP      string
+	ServerIP      string
+	Referer       string
+	Latency       time.Duration
+	Protocol      string
+}
+
+func (r *HTTPRequest) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	addString := func(key, value string) {
+		if value != "" {
+			enc.AddString(key, value)
+		}
+	}
+	// int64 values are strings in the JSON representation of LogEntry.
+	addInt64 := func(key string, value int64) {
+		if value > 0 {
+			enc.AddString(key, strconv.FormatInt(value, 10))
+		}
+	}
+
+	addString("requestMethod", r.RequestMethod)
+	addString("requestUrl", r.RequestURL)
+	addInt64("requestSize", r.RequestSize)
+	if r.Status != 0 {
+		enc.AddInt("status", r.Status)
+	}
+	addInt64("responseSize", r.ResponseSize)
+	addString("userAgent", r.UserAgent)
+	addString("remoteIp", r.RemoteIP)
+	addString("serverIp", r.ServerIP)
+	addString("referer", r.Referer)
+	if r.Latency != 0 {
+		enc.AddString("latency", FormatLatency(r.Latency))
+	}
+	addString("protocol", r.Protocol)
+	return nil
+}
+
+// HTTPRequestField returns a zap.Field that sets r as the httpRequest of the entry.
+func HTTPRequestField(r *HTTPRequest) zap.Field {
+	return zap.Object(HTTPRequestKey, r)
+}
+
+// statusLevel returns the level of a response with status:
+// ERROR for 5xx, WARNING for 4xx and INFO for the others.
+func statusLevel(status int) zapcore.Level {
+	switch {
+	case status >= 500:
+		return zapcore.ErrorLevel
+	case status >= 400:
+		return zapcore.WarnLevel
+	default:
+		return zapcore.InfoLevel
+	}
+}
+
+type problemDetails struct {
+	status int
+	title  string
+	detail string
+}
+
+func (p problemDetails) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("type", "about:blank")
+	enc.AddString("title", p.title)
+	enc.AddInt("status", p.status)
+	if p.detail != "" {
+		enc.AddString("detail", p.detail)
+	}
+	return nil
+}
+
+// ProblemDetails returns the fields for an error response of an API with status:
+// the problem details of RFC 7807 as the problem object, and status as the status of httpRequest.
+// The severity is overridden by status: ERROR for 5xx, WARNING for 4xx and INFO for the others.
+//
+// https://www.rfc-editor.org/rfc/rfc7807
+func ProblemDetails(status int, title, detail string) []zap.Field {
+	return []zap.Field{
+		HTTPRequestField(&HTTPRequest{Status: status}),
+		zap.Object("problem", problemDetails{status: status, title: title, detail: detail}),
+		Severity(statusLevel(status)),
+	}
+}
@@ -0,0 +1,86 @@
+package zapcloudlogging
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHTTPRequestField(t *testing.T) {
+	tests := []struct {
+		name string
+		r    *HTTPRequest
+		want map[string]any
+	}{
+		{name: "zero", r: &HTTPRequest{}, want: map[string]any{}},
+		{
+			name: "full",
+			r: &HTTPRequest{
+				RequestMethod: "POST",
+				RequestURL:    "https://example.com/items",
+				RequestSize:   123,
+				Status:        201,
+				ResponseSize:  45,
+				UserAgent:     "test",
+				RemoteIP:      "192.0.2.1",
+				ServerIP:      "192.0.2.2",
+				Referer:       "https://example.com/",
+				Latency:       1500 * time.Millisecond,
+				Protocol:      "HTTP/1.1",
+			},
+			want: map[string]any{
+				"requestMethod": "POST",
+				"requestUrl":    "https://example.com/items",
+				"requestSize":   "123",
+				"status":        201,
+				"responseSize":  "45",
+				"userAgent":     "test",
+				"remoteIp":      "192.0.2.1",
+				"serverIp":      "192.0.2.2",
+				"referer":       "https://example.com/",
+				"latency":       "1.500s",
+				"protocol":      "HTTP/1.1",
+			},
+		},
+		{
+			name: "negative sizes",
+			r:    &HTTPRequest{RequestMethod: "GET", RequestSize: -1, ResponseSize: -1},
+			want: map[string]any{"requestMethod": "GET"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := encodeFields(t, HTTPRequestField(tt.r))
+			assertJSON(t, HTTPRequestKey, got[HTTPRequestKey], tt.want)
+		})
+	}
+}
+
+func TestProblemDetails(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		title        string
+		detail       string
+		wantSeverity string
+	}{
+		{name: "server error", status: 503, title: "Service Unavailable", detail: "database is down", wantSeverity: "ERROR"},
+		{name: "client error", status: 404, title: "Not Found", detail: "no item 42", wantSeverity: "WARNING"},
+		{name: "no detail", status: 409, title: "Conflict", wantSeverity: "WARNING"},
+		{name: "redirect", status: 303, title: "See Other", wantSeverity: "INFO"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, sink := newTestLogger(t)
+			logger.Error("response", ProblemDetails(tt.status, tt.title, tt.detail)...)
+
+			got := sink.entries(t)[0]
+			assertJSON(t, SeverityKey, got[SeverityKey], tt.wantSeverity)
+			assertJSON(t, HTTPRequestKey, got[HTTPRequestKey], map[string]any{"status": tt.status})
+			want := map[string]any{"type": "about:blank", "title": tt.title, "status": tt.status}
+			if tt.detail != "" {
+				want["detail"] = tt.detail
+			}
+			assertJSON(t, "problem", got["problem"], want)
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package zapcloudlogging
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+)
+
+// fileScheme is the URL scheme of the file sink of NewHybridConfig.
+const fileScheme = "cloudlogging-file"
+
+var (
+	// fileOpeners are the functions set by WithFileOpener, keyed by the opener parameter of the sink URLs.
+	fileOpeners   sync.Map
+	fileOpenerSeq uint64
+)
+
+func init() {
+	if err := zap.RegisterSink(fileScheme, newFileSink); err != nil {
+		panic(err)
+	}
+}
+
+// openFile opens path for appending, like zap does for file paths.
+func openFile(path string) (io.WriteCloser, error) {
+	return os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0666)
+}
+
+type hybridOptions struct {
+	open func(path string) (io.WriteCloser, error)
+}
+
+// A HybridOption configures the config returned by NewHybridConfig.
+type HybridOption func(*hybridOptions)
+
+// WithFileOpener sets the function that opens the file, e.g. to rotate the file with a *lumberjack.Logger:
+//
+//	cfg := zapcloudlogging.NewHybridConfig("app.log", zapcloudlogging.WithFileOpener(func(path string) (io.WriteCloser, error) {
+//		return &lumberjack.Logger{Filename: path, MaxSize: 100}, nil
+//	}))
+//
+// By default, the file is opened for appending.
+func WithFileOpener(open func(path string) (io.WriteCloser, error)) HybridOption {
+	return func(o *hybridOptions) {
+		o.open = open
+	}
+}
+
+// fileSink is a zap.Sink writing to a io.WriteCloser, which is synced if it has a Sync method.
+type fileSink struct {
+	io.WriteCloser
+}
+
+func (s fileSink) Sync() error {
+	if s, ok := s.WriteCloser.(interface{ Sync() error }); ok {
+		return s.Sync()
+	}
+	return nil
+}
+
+func newFileSink(u *url.URL) (zap.Sink, error) {
+	path := u.Path
+	if u.Opaque != "" {
+		// The path is escaped as opaque by NewHybridConfig, so that relative paths are not parsed as hosts.
+		var err error
+		if path, err = url.PathUnescape(u.Opaque); err != nil {
+			return nil, err
+		}
+	}
+
+	open := openFile
+	if id := u.Query().Get("opener"); id != "" {
+		f, ok := fileOpeners.Load(id)
+		if !ok {
+			return nil, fmt.Errorf("zapcloudlogging: unknown file opener %q", id)
+		}
+		open = f.(func(string) (io.WriteCloser, error))
+	}
+
+	w, err := open(path)
+	if err != nil {
+		return nil, err
+	}
+	return fileSink{w}, nil
+}
+
+// NewHybridConfig returns a zapcore.Config for production environments that writes entries
+// both to stderr for Cloud Logging and to the file at filePath, e.g. to keep local copies on premises.
+// Both outputs share the encoder, so the file has the same JSON as Cloud Logging.
+// The file is opened for appending, or by the function set by WithFileOpener, which can rotate it.
+func NewHybridConfig(filePath string, opts ...HybridOption) zap.Config {
+	var o hybridOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	u := fileScheme + ":" + url.PathEscape(filePath)
+	if o.open != nil {
+		// The opener is kept for the sink URL, which can be built any number of times.
+		id := strconv.FormatUint(atomic.AddUint64(&fileOpenerSeq, 1), 10)
+		fileOpeners.Store(id, o.open)
+		u += "?opener=" + id
+	}
+	cfg := NewProductionConfig()
+	cfg.OutputPaths = []string{"stderr", u}
+	return cfg
+}
@@ -0,0 +1,139 @@
+package zapcloudlogging
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// recordingFile is a file of a custom opener of WithFileOpener, recording whether it's synced.
+type recordingFile struct {
+	testSink
+	synced bool
+}
+
+func (f *recordingFile) Sync() error {
+	f.synced = true
+	return nil
+}
+
+func TestNewHybridConfig(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name     string
+		filePath string
+		custom   bool
+	}{
+		{name: "absolute", filePath: filepath.Join(dir, "app.log")},
+		{name: "spaces", filePath: filepath.Join(dir, "my logs", "app.log")},
+		{name: "custom opener", filePath: "logs/app.log", custom: true},
+		{name: "custom opener with escapes", filePath: "logs/a%20b?.log", custom: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				opened string
+				file   = &recordingFile{}
+				opts   []HybridOption
+			)
+			if tt.custom {
+				opts = append(opts, WithFileOpener(func(path string) (io.WriteCloser, error) {
+					opened = path
+					return file, nil
+				}))
+			} else if err := os.MkdirAll(filepath.Dir(tt.filePath), 0o755); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg := NewHybridConfig(tt.filePath, opts...)
+			stderrPath, stderr := newTestSinkPath(t)
+			cfg.OutputPaths[0] = stderrPath
+			cfg.EncoderConfig.TimeKey = ""
+			logger, err := cfg.Build()
+			if err != nil {
+				t.Fatalf("Build() = %v", err)
+			}
+			logger.Info("msg")
+			if err := logger.Sync(); err != nil {
+				t.Fatalf("Sync() = %v", err)
+			}
+
+			var got string
+			if tt.custom {
+				if opened != tt.filePath {
+					t.Errorf("opened %q, want %q", opened, tt.filePath)
+				}
+				if !file.synced {
+					t.Error("file isn't synced")
+				}
+				got = file.String()
+			} else {
+				b, err := os.ReadFile(tt.filePath)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = string(b)
+			}
+			if want := stderr.String(); got != want || want == "" {
+				t.Errorf("file = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestNewHybridConfigOpenError(t *testing.T) {
+	errOpen := errors.New("open failed")
+	cfg := NewHybridConfig("app.log", WithFileOpener(func(string) (io.WriteCloser, error) {
+		return nil, errOpen
+	}))
+	cfg.OutputPaths[0], _ = newTestSinkPath(t)
+	if _, err := cfg.Build(); !errors.Is(err, errOpen) {
+		t.Errorf("Build() error = %v, want %v", err, errOpen)
+	}
+}
+
+func TestWithFileOpener(t *testing.T) {
+	opener := func(file io.WriteCloser) HybridOption {
+		return WithFileOpener(func(string) (io.WriteCloser, error) {
+			return file, nil
+		})
+	}
+	a, b := &recordingFile{}, &recordingFile{}
+	cfgA := NewHybridConfig("a.log", opener(a))
+	cfgB := NewHybridConfig("b.log", opener(b))
+
+	for _, tt := range []struct {
+		cfg  zap.Config
+		msg  string
+		file *recordingFile
+	}{
+		{cfg: cfgA, msg: "a", file: a},
+		{cfg: cfgB, msg: "b", file: b},
+		// A config is built again with its own opener.
+		{cfg: cfgA, msg: "again", file: a},
+	} {
+		tt.cfg.OutputPaths[0], _ = newTestSinkPath(t)
+		logger, err := tt.cfg.Build()
+		if err != nil {
+			t.Fatalf("Build() = %v", err)
+		}
+		logger.Info(tt.msg)
+	}
+
+	assertStrings(t, messagesOf(t, a.String()), []string{"a", "again"})
+	assertStrings(t, messagesOf(t, b.String()), []string{"b"})
+}
+
+// messagesOf returns the messages of the entries encoded in s.
+func messagesOf(t *testing.T, s string) []string {
+	t.Helper()
+	var msgs []string
+	for _, e := range decodeEntries(t, s) {
+		msgs = append(msgs, e[MessageKey].(string))
+	}
+	return msgs
+}
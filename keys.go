@@ -0,0 +1,65 @@
+package zapcloudlogging
+
+import (
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// Keys of the special fields in structured logging.
+//
+// https://cloud.google.com/logging/docs/structured-logging#special-payload-fields
+const (
+	SeverityKey       = "severity"
+	MessageKey        = "message"
+	TimestampKey      = "timestamp"
+	LoggerKey         = "logger"
+	StacktraceKey     = "stacktrace"
+	HTTPRequestKey    = "httpRequest"
+	InsertIDKey       = "logging.googleapis.com/insertId"
+	LabelsKey         = "logging.googleapis.com/labels"
+	OperationKey      = "logging.googleapis.com/operation"
+	SourceLocationKey = "logging.googleapis.com/sourceLocation"
+	SpanIDKey         = "logging.googleapis.com/spanId"
+	TraceKey          = "logging.googleapis.com/trace"
+	TraceSampledKey   = "logging.googleapis.com/trace_sampled"
+)
+
+const reservedKeyPrefix = "logging.googleapis.com/"
+
+var reservedKeys = map[string]struct{}{
+	SeverityKey:    {},
+	MessageKey:     {},
+	TimestampKey:   {},
+	LoggerKey:      {},
+	StacktraceKey:  {},
+	HTTPRequestKey: {},
+}
+
+// IsReservedKey reports whether key is a key that has a special meaning in Cloud Logging
+// or is used by the encoder of this package.
+// All keys starting with "logging.googleapis.com/" are reserved.
+func IsReservedKey(key string) bool {
+	if strings.HasPrefix(key, reservedKeyPrefix) {
+		return true
+	}
+	_, ok := reservedKeys[key]
+	return ok
+}
+
+// MergeFields returns the fields of a followed by the fields of b, except the fields of a with a reserved key,
+// see IsReservedKey, also set by b, e.g. when both the fields of the context and of the call site have a trace,
+// so that the entry has a single field with the key, the one of b.
+// Labels are not deduplicated since they're merged by WrapCore, where the labels of b win.
+// The other fields, and the duplicates within a or b, are kept as they are.
+func MergeFields(a, b []zap.Field) []zap.Field {
+	a, b = flatten(a), flatten(b)
+	merged := make([]zap.Field, 0, len(a)+len(b))
+	for _, f := range a {
+		if f.Key != LabelsKey && IsReservedKey(f.Key) && hasField(b, f.Key) {
+			continue
+		}
+		merged = append(merged, f)
+	}
+	return append(merged, b...)
+}
@@ -0,0 +1,97 @@
+package zapcloudlogging
+
+import (
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestIsReservedKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{key: SeverityKey, want: true},
+		{key: MessageKey, want: true},
+		{key: TimestampKey, want: true},
+		{key: LoggerKey, want: true},
+		{key: StacktraceKey, want: true},
+		{key: HTTPRequestKey, want: true},
+		{key: TraceKey, want: true},
+		{key: LabelsKey, want: true},
+		{key: "logging.googleapis.com/unknown", want: true},
+		{key: "user", want: false},
+		{key: "Severity", want: false},
+		{key: "logging.googleapis.com", want: false},
+		{key: "", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if got := IsReservedKey(tt.key); got != tt.want {
+				t.Errorf("IsReservedKey(%q) = %v, want %v", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeFields(t *testing.T) {
+	ctxTrace := zap.String(TraceKey, "projects/p/traces/ctx")
+	callTrace := zap.String(TraceKey, "projects/p/traces/call")
+	tests := []struct {
+		name     string
+		a, b     []zap.Field
+		wantKeys []string
+		want     map[string]any
+	}{
+		{name: "empty"},
+		{
+			name:     "distinct",
+			a:        []zap.Field{zap.String("user", "u1")},
+			b:        []zap.Field{zap.Int("items", 3)},
+			wantKeys: []string{"user", "items"},
+			want:     map[string]any{"user": "u1", "items": 3},
+		},
+		{
+			name:     "reserved key of b wins",
+			a:        []zap.Field{ctxTrace, zap.String(SpanIDKey, testSpanID)},
+			b:        []zap.Field{callTrace},
+			wantKeys: []string{SpanIDKey, TraceKey},
+			want:     map[string]any{TraceKey: "projects/p/traces/call", SpanIDKey: testSpanID},
+		},
+		{
+			name:     "other keys kept",
+			a:        []zap.Field{zap.String("user", "u1")},
+			b:        []zap.Field{zap.String("user", "u2")},
+			wantKeys: []string{"user", "user"},
+		},
+		{
+			name:     "labels merged",
+			a:        []zap.Field{Label("env", "prod"), Label("region", "asia")},
+			b:        []zap.Field{Label("env", "dev")},
+			wantKeys: []string{LabelsKey, LabelsKey, LabelsKey},
+			want:     map[string]any{LabelsKey: map[string]string{"env": "dev", "region": "asia"}},
+		},
+		{
+			name:     "grouped",
+			a:        []zap.Field{group(ctxTrace, zap.String("user", "u1"))},
+			b:        []zap.Field{group(callTrace)},
+			wantKeys: []string{"user", TraceKey},
+			want:     map[string]any{TraceKey: "projects/p/traces/call", "user": "u1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged := MergeFields(tt.a, tt.b)
+
+			var keys []string
+			for _, f := range merged {
+				keys = append(keys, f.Key)
+			}
+			assertStrings(t, keys, tt.wantKeys)
+			got := encodeFields(t, merged...)
+			for k, v := range tt.want {
+				assertJSON(t, k, got[k], v)
+			}
+		})
+	}
+}
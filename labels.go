@@ -0,0 +1,272 @@
+package zapcloudlogging
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type label struct {
+	key   string
+	value string
+}
+
+// labels is the value of the logging.googleapis.com/labels field.
+//
+// https://cloud.google.com/logging/docs/structured-logging#special-payload-fields
+type labels []label
+
+func (ls labels) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for _, l := range ls {
+		enc.AddString(l.key, l.value)
+	}
+	return nil
+}
+
+// merge returns labels with the labels of src added.
+// A label of src replaces the label with the same key.
+func (ls labels) merge(src labels) labels {
+	merged := make(labels, len(ls), len(ls)+len(src))
+	copy(merged, ls)
+	for _, l := range src {
+		replaced := false
+		for i := range merged {
+			if merged[i].key == l.key {
+				merged[i].value = l.value
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			merged = append(merged, l)
+		}
+	}
+	return merged
+}
+
+// sorted returns a copy of ls ordered by key.
+func (ls labels) sorted() labels {
+	sorted := append(labels(nil), ls...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].key < sorted[j].key
+	})
+	return sorted
+}
+
+// Label returns a zap.Field that sets a label of the entry.
+//
+// Cloud Logging reads labels from a single logging.googleapis.com/labels object,
+// so labels set by multiple fields are merged by WrapCore, which wraps the loggers built by
+// NewProduction and NewDevelopment.
+func Label(key, value string) zap.Field {
+	return zap.Object(LabelsKey, labels{{key: key, value: value}})
+}
+
+// Labels returns a zap.Field that sets labels of the entry, ordered by key.
+//
+// Cloud Logging reads labels from a single logging.googleapis.com/labels object,
+// so labels set by multiple fields are merged by WrapCore, which wraps the loggers built by
+// NewProduction and NewDevelopment.
+func Labels(m map[string]string) zap.Field {
+	return zap.Object(LabelsKey, labelsFromMap(m))
+}
+
+// LabelsAny returns a zap.Field that sets labels of the entry like Labels,
+// with the values converted to strings, since Cloud Logging rejects labels with other values:
+// integers and floats are formatted with strconv, and booleans as "true" or "false".
+//
+// Labels can't be nested, so other values, e.g. maps, are formatted with fmt.Sprint.
+func LabelsAny(m map[string]any) zap.Field {
+	strs := make(map[string]string, len(m))
+	for k, v := range m {
+		strs[k] = labelValue(v)
+	}
+	return Labels(strs)
+}
+
+// labelValue formats v as a label value.
+func labelValue(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case fmt.Stringer:
+		return v.String()
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Bool:
+		return strconv.FormatBool(rv.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(rv.Uint(), 10)
+	case reflect.Float32:
+		return strconv.FormatFloat(rv.Float(), 'g', -1, 32)
+	case reflect.Float64:
+		return strconv.FormatFloat(rv.Float(), 'g', -1, 64)
+	case reflect.String:
+		return rv.String()
+	}
+	return fmt.Sprint(v)
+}
+
+// labelsFromMap returns the labels of m ordered by key.
+func labelsFromMap(m map[string]string) labels {
+	ls := make(labels, 0, len(m))
+	for k, v := range m {
+		ls = append(ls, label{key: k, value: v})
+	}
+	sort.Slice(ls, func(i, j int) bool {
+		return ls[i].key < ls[j].key
+	})
+	return ls
+}
+
+// labelsOf returns the labels set by f.
+func labelsOf(f zapcore.Field) (labels, bool) {
+	if f.Key != LabelsKey || f.Type != zapcore.ObjectMarshalerType {
+		return nil, false
+	}
+	ls, ok := f.Interface.(labels)
+	return ls, ok
+}
+
+// fieldGroup is a set of fields logged as a single zap.Field.
+type fieldGroup []zapcore.Field
+
+func (g fieldGroup) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for _, f := range g {
+		f.AddTo(enc)
+	}
+	return nil
+}
+
+// group returns a zap.Field that logs fields inline.
+// WrapCore unpacks the fields, so labels set by them are merged.
+func group(fields ...zap.Field) zap.Field {
+	return zap.Inline(fieldGroup(fields))
+}
+
+// flatten returns fields with the fields created by group unpacked.
+func flatten(fields []zapcore.Field) []zapcore.Field {
+	n := -1
+	for i, f := range fields {
+		if _, ok := f.Interface.(fieldGroup); ok && f.Type == zapcore.InlineMarshalerType {
+			n = i
+			break
+		}
+	}
+	if n < 0 {
+		return fields
+	}
+
+	flat := append([]zapcore.Field(nil), fields[:n]...)
+	for _, f := range fields[n:] {
+		if g, ok := f.Interface.(fieldGroup); ok && f.Type == zapcore.InlineMarshalerType {
+			flat = append(flat, flatten(g)...)
+		} else {
+			flat = append(flat, f)
+		}
+	}
+	return flat
+}
+
+// extractLabels merges the labels set by fields into ls,
+// and returns them with the rest of the fields.
+func extractLabels(ls labels, fields []zapcore.Field) (labels, []zapcore.Field) {
+	n := 0
+	for _, f := range fields {
+		if _, ok := labelsOf(f); ok {
+			n++
+		}
+	}
+	if n == 0 {
+		return ls, fields
+	}
+
+	rest := make([]zapcore.Field, 0, len(fields)-n)
+	for _, f := range fields {
+		if l, ok := labelsOf(f); ok {
+			ls = ls.merge(l)
+		} else {
+			rest = append(rest, f)
+		}
+	}
+	return ls, rest
+}
+
+// withLabels returns an Option that sets ls to every entry.
+func withLabels(ls labels) Option {
+	return optionFunc(func(s *settings) {
+		s.labels = s.labels.merge(ls)
+	})
+}
+
+// WithRequiredLabels returns an Option that ensures every entry has the labels of defaults,
+// e.g. the env and service labels mandated by a logging standard.
+// A label missing in the entry is set to the default value,
+// and a label set by a field, including the labels set by other Options, is kept as is.
+func WithRequiredLabels(defaults map[string]string) Option {
+	return optionFunc(func(s *settings) {
+		s.defaultLabels = s.defaultLabels.merge(labelsFromMap(defaults))
+	})
+}
+
+// WithService returns an Option that sets name as the service label of every entry.
+// If name is empty, the K_SERVICE environment variable set by Cloud Run,
+// or the SERVICE_NAME environment variable is used.
+func WithService(name string) Option {
+	if name == "" {
+		name = os.Getenv("K_SERVICE")
+	}
+	if name == "" {
+		name = os.Getenv("SERVICE_NAME")
+	}
+
+	var ls labels
+	if name != "" {
+		ls = labels{{key: "service", value: name}}
+	}
+	return withLabels(ls)
+}
+
+// WithComponentLabel returns an Option that sets the first element of the logger name
+// as the component label of every entry, e.g. "db" for a logger named "db.pool".
+// Entries of unnamed loggers have no component label.
+func WithComponentLabel() Option {
+	return wrapCore(func(core zapcore.Core) zapcore.Core {
+		return WithTraceCore(core, func(ent zapcore.Entry) []zapcore.Field {
+			if ent.LoggerName == "" {
+				return nil
+			}
+			component := ent.LoggerName
+			if i := strings.IndexByte(component, '.'); i >= 0 {
+				component = component[:i]
+			}
+			return []zapcore.Field{Label("component", component)}
+		})
+	})
+}
+
+// processStart is the time the process started, approximated by the time the package was initialized.
+var processStart = time.Now()
+
+// WithUptime returns an Option that sets the number of seconds the process had been running
+// when the entry was logged as the uptime_seconds label of every entry,
+// e.g. to tell how long the process ran before it crashed.
+func WithUptime() Option {
+	return wrapCore(func(core zapcore.Core) zapcore.Core {
+		return WithTraceCore(core, func(ent zapcore.Entry) []zapcore.Field {
+			uptime := int64(ent.Time.Sub(processStart) / time.Second)
+			return []zapcore.Field{Label("uptime_seconds", strconv.FormatInt(uptime, 10))}
+		})
+	})
+}
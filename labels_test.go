@@ -0,0 +1,222 @@
+package zapcloudlogging
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestLabel(t *testing.T) {
+	tests := []struct {
+		name   string
+		with   []zap.Field
+		fields []zap.Field
+		want   any
+	}{
+		{name: "no labels", fields: []zap.Field{zap.String("k", "v")}, want: nil},
+		{name: "label", fields: []zap.Field{Label("env", "prod")}, want: map[string]string{"env": "prod"}},
+		{
+			name:   "merged",
+			fields: []zap.Field{Label("env", "prod"), Labels(map[string]string{"b": "2", "a": "1"})},
+			want:   map[string]string{"env": "prod", "a": "1", "b": "2"},
+		},
+		{
+			name:   "later wins",
+			fields: []zap.Field{Label("env", "prod"), Label("env", "dev")},
+			want:   map[string]string{"env": "dev"},
+		},
+		{
+			name:   "with",
+			with:   []zap.Field{Label("env", "prod"), Label("region", "asia")},
+			fields: []zap.Field{Label("env", "dev")},
+			want:   map[string]string{"env": "dev", "region": "asia"},
+		},
+		{name: "empty labels", fields: []zap.Field{Labels(nil)}, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, sink := newTestCore(t)
+			zap.New(WrapCore(core)).With(tt.with...).Info("msg", tt.fields...)
+
+			entries := sink.entries(t)
+			if len(entries) != 1 {
+				t.Fatalf("got %d entries, want 1", len(entries))
+			}
+			assertJSON(t, "labels", entries[0][LabelsKey], tt.want)
+		})
+	}
+}
+
+func TestWithService(t *testing.T) {
+	tests := []struct {
+		name      string
+		service   string
+		kService  string
+		envName   string
+		wantLabel any
+	}{
+		{name: "name", service: "api", kService: "run", envName: "env", wantLabel: map[string]string{"service": "api"}},
+		{name: "K_SERVICE", kService: "run", envName: "env", wantLabel: map[string]string{"service": "run"}},
+		{name: "SERVICE_NAME", envName: "env", wantLabel: map[string]string{"service": "env"}},
+		{name: "none", wantLabel: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("K_SERVICE", tt.kService)
+			t.Setenv("SERVICE_NAME", tt.envName)
+
+			logger, sink := newTestLogger(t, WithService(tt.service))
+			logger.Info("msg")
+
+			assertJSON(t, "labels", sink.entries(t)[0][LabelsKey], tt.wantLabel)
+		})
+	}
+}
+
+func TestWithLabelsMerged(t *testing.T) {
+	tests := []struct {
+		name   string
+		with   []zap.Field
+		fields []zap.Field
+		want   map[string]string
+	}{
+		{name: "options", want: map[string]string{"service": "b"}},
+		{name: "with", with: []zap.Field{Label("env", "prod")}, want: map[string]string{"service": "b", "env": "prod"}},
+		{name: "entry", fields: []zap.Field{Label("service", "c")}, want: map[string]string{"service": "c"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, sink := newTestLogger(t, WithService("a"), WithService("b"))
+			// The labels of the Options are merged into the reservedCore of build, not wrapped by another one.
+			c, ok := logger.Core().(*reservedCore)
+			if !ok {
+				t.Fatalf("core = %T, want *reservedCore", logger.Core())
+			}
+			if inner, ok := c.Core.(*reservedCore); ok {
+				t.Fatalf("core wraps another %T", inner)
+			}
+
+			logger.With(tt.with...).Info("msg", tt.fields...)
+			assertJSON(t, "labels", sink.entries(t)[0][LabelsKey], tt.want)
+		})
+	}
+}
+
+func TestWithComponentLabel(t *testing.T) {
+	tests := []struct {
+		name       string
+		loggerName string
+		want       any
+	}{
+		{name: "unnamed", want: nil},
+		{name: "named", loggerName: "db", want: map[string]string{"component": "db"}},
+		{name: "nested", loggerName: "db.pool", want: map[string]string{"component": "db"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, sink := newTestLogger(t, WithComponentLabel())
+			if tt.loggerName != "" {
+				logger = logger.Named(tt.loggerName)
+			}
+			logger.Info("msg")
+
+			assertJSON(t, "labels", sink.entries(t)[0][LabelsKey], tt.want)
+		})
+	}
+}
+
+func TestWithUptime(t *testing.T) {
+	tests := []struct {
+		name   string
+		uptime time.Duration
+		want   string
+	}{
+		{name: "just started", uptime: 0, want: "0"},
+		{name: "seconds", uptime: 90 * time.Second, want: "90"},
+		{name: "hours", uptime: 2 * time.Hour, want: "7200"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := processStart
+			processStart = time.Now().Add(-tt.uptime)
+			t.Cleanup(func() { processStart = start })
+
+			logger, sink := newTestLogger(t, WithUptime())
+			logger.Info("msg")
+
+			assertJSON(t, "labels", sink.entries(t)[0][LabelsKey], map[string]string{"uptime_seconds": tt.want})
+		})
+	}
+}
+
+func TestWithRequiredLabels(t *testing.T) {
+	defaults := map[string]string{"env": "unknown", "team": "platform"}
+	tests := []struct {
+		name string
+		opts []Option
+		with []zap.Field
+		log  []zap.Field
+		want map[string]string
+	}{
+		{name: "defaults", want: map[string]string{"env": "unknown", "team": "platform"}},
+		{
+			name: "field",
+			log:  []zap.Field{Label("env", "prod")},
+			want: map[string]string{"env": "prod", "team": "platform"},
+		},
+		{
+			name: "with",
+			with: []zap.Field{Label("team", "search")},
+			want: map[string]string{"env": "unknown", "team": "search"},
+		},
+		{
+			name: "option",
+			opts: []Option{WithService("api")},
+			want: map[string]string{"env": "unknown", "service": "api", "team": "platform"},
+		},
+		{
+			name: "merged",
+			opts: []Option{WithRequiredLabels(map[string]string{"env": "dev", "region": "asia"})},
+			want: map[string]string{"env": "dev", "region": "asia", "team": "platform"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, sink := newTestLogger(t, append([]Option{WithRequiredLabels(defaults)}, tt.opts...)...)
+			logger.With(tt.with...).Info("msg", tt.log...)
+
+			assertJSON(t, "labels", sink.entries(t)[0][LabelsKey], tt.want)
+		})
+	}
+}
+
+type testEnv string
+
+func TestLabelsAny(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+		want  string
+	}{
+		{name: "string", value: "prod", want: "prod"},
+		{name: "int", value: 42, want: "42"},
+		{name: "negative", value: int8(-1), want: "-1"},
+		{name: "uint", value: uint64(18446744073709551615), want: "18446744073709551615"},
+		{name: "float32", value: float32(0.1), want: "0.1"},
+		{name: "float64", value: 1.5, want: "1.5"},
+		{name: "bool", value: true, want: "true"},
+		{name: "stringer", value: time.Minute, want: "1m0s"},
+		{name: "named string", value: testEnv("dev"), want: "dev"},
+		{name: "map", value: map[string]int{"a": 1}, want: "map[a:1]"},
+		{name: "nil", value: nil, want: "<nil>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, sink := newTestCore(t)
+			zap.New(WrapCore(core)).Info("msg", LabelsAny(map[string]any{"k": tt.value}))
+
+			assertJSON(t, "labels", sink.entries(t)[0][LabelsKey], map[string]string{"k": tt.want})
+		})
+	}
+}
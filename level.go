@@ -0,0 +1,268 @@
+package zapcloudlogging
+
+import (
+	"os"
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// MinSeverityEnv is the environment variable read by LevelFromEnv.
+const MinSeverityEnv = "GOOGLE_CLOUD_LOG_MIN_SEVERITY"
+
+var severityLogLevel = map[string]zapcore.Level{
+	"DEFAULT":   zapcore.DebugLevel,
+	"DEBUG":     zapcore.DebugLevel,
+	"INFO":      zapcore.InfoLevel,
+	"NOTICE":    zapcore.InfoLevel,
+	"WARNING":   zapcore.WarnLevel,
+	"ERROR":     zapcore.ErrorLevel,
+	"CRITICAL":  zapcore.DPanicLevel,
+	"ALERT":     zapcore.PanicLevel,
+	"EMERGENCY": zapcore.FatalLevel,
+}
+
+// LevelFromEnv returns a zap.AtomicLevel enabled at the Cloud Logging severity,
+// such as WARNING or ERROR, set in the GOOGLE_CLOUD_LOG_MIN_SEVERITY environment variable.
+// NOTICE is mapped to INFO, and DEFAULT to DEBUG.
+// If the variable is not set or is unknown, INFO is used.
+func LevelFromEnv() zap.AtomicLevel {
+	l, ok := LevelOf(strings.TrimSpace(os.Getenv(MinSeverityEnv)))
+	if !ok {
+		l = zapcore.InfoLevel
+	}
+	return zap.NewAtomicLevelAt(l)
+}
+
+// SeverityOf returns the Cloud Logging severity of l.
+// If l is not a level defined by zapcore, DEFAULT is returned.
+//
+// https://cloud.google.com/logging/docs/reference/v2/rest/v2/LogEntry#logseverity
+func SeverityOf(l zapcore.Level) string {
+	if s, ok := logLevelSeverity[l]; ok {
+		return s
+	}
+	return "DEFAULT"
+}
+
+// LevelOf returns the zapcore.Level of the Cloud Logging severity, case-insensitively.
+// Severities without a corresponding level are mapped to the closest one:
+// NOTICE to zapcore.InfoLevel and DEFAULT to zapcore.DebugLevel.
+// If severity is unknown, LevelOf returns false.
+func LevelOf(severity string) (zapcore.Level, bool) {
+	l, ok := severityLogLevel[strings.ToUpper(severity)]
+	return l, ok
+}
+
+// severityNumber is the number of each severity of LogSeverity.
+//
+// https://cloud.google.com/logging/docs/reference/v2/rest/v2/LogEntry#logseverity
+var severityNumber = map[string]int64{
+	"DEFAULT":   0,
+	"DEBUG":     100,
+	"INFO":      200,
+	"NOTICE":    300,
+	"WARNING":   400,
+	"ERROR":     500,
+	"CRITICAL":  600,
+	"ALERT":     700,
+	"EMERGENCY": 800,
+}
+
+// WithSeverityNumberField returns an Option that logs the number of the severity of LogSeverity,
+// e.g. 400 for WARNING, as the key field of every entry in addition to the severity,
+// e.g. for range queries of the logs exported to BigQuery.
+func WithSeverityNumberField(key string) Option {
+	return optionFunc(func(s *settings) {
+		s.severityNumberKey = key
+	})
+}
+
+// severityOverride is the value of a field created by Severity.
+type severityOverride zapcore.Level
+
+// Severity returns a zap.Field that overrides the severity of the entry with l.
+// It's applied by WrapCore, and the last one wins.
+//
+// The entry must still be enabled at the level it's logged at,
+// and overriding it with zapcore.PanicLevel or zapcore.FatalLevel doesn't panic or exit.
+func Severity(l zapcore.Level) zap.Field {
+	return zap.Field{Type: zapcore.SkipType, Interface: severityOverride(l)}
+}
+
+// severityOf returns the level set by the last Severity field in fields.
+func severityOf(fields []zapcore.Field) (zapcore.Level, bool) {
+	for i := len(fields) - 1; i >= 0; i-- {
+		if l, ok := fields[i].Interface.(severityOverride); ok && fields[i].Type == zapcore.SkipType {
+			return zapcore.Level(l), true
+		}
+	}
+	return 0, false
+}
+
+// WithErrorSeverityClassifier returns an Option that overrides the severity of entries with an error field,
+// e.g. zap.Error, with the level returned by classify, unless the severity is set by Severity.
+// If classify returns false, the severity is not changed.
+// The first error field of the entry is classified, or the first one added by With if the entry has none.
+func WithErrorSeverityClassifier(classify func(error) (zapcore.Level, bool)) Option {
+	return wrapCore(func(core zapcore.Core) zapcore.Core {
+		return &classifierCore{Core: core, classify: classify}
+	})
+}
+
+type classifierCore struct {
+	zapcore.Core
+	classify func(error) (zapcore.Level, bool)
+	// err is the first error added by With, if any.
+	err error
+}
+
+func (c *classifierCore) With(fields []zapcore.Field) zapcore.Core {
+	err := c.err
+	if err == nil {
+		err, _ = firstError(flatten(fields))
+	}
+	return &classifierCore{
+		Core:     c.Core.With(fields),
+		classify: c.classify,
+		err:      err,
+	}
+}
+
+func (c *classifierCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return checkRewrite(c.Core, ent, ce, c)
+}
+
+func (c *classifierCore) rewrite(ent *zapcore.Entry, fields []zapcore.Field) ([]zapcore.Field, bool) {
+	fields = flatten(fields)
+	if _, ok := severityOf(fields); ok {
+		return fields, true
+	}
+	err, ok := firstError(fields)
+	if !ok {
+		err, ok = c.err, c.err != nil
+	}
+	if ok {
+		if l, ok := c.classify(err); ok {
+			ent.Level = l
+		}
+	}
+	return fields, true
+}
+
+// firstError returns the error of the first error field in fields.
+func firstError(fields []zapcore.Field) (error, bool) {
+	for _, f := range fields {
+		if err, ok := errorOf(f); ok {
+			return err, true
+		}
+	}
+	return nil, false
+}
+
+// A SeverityRule overrides the severity of the entries whose message matches it.
+type SeverityRule struct {
+	// Contains matches messages containing it, if it's not empty.
+	Contains string
+	// Regexp matches messages matched by it, if it's not nil.
+	Regexp *regexp.Regexp
+	// Level is the level the severity is overridden with.
+	Level zapcore.Level
+}
+
+// match reports whether msg matches r. A rule with both Contains and Regexp needs both to match.
+func (r SeverityRule) match(msg string) bool {
+	if r.Contains == "" && r.Regexp == nil {
+		return false
+	}
+	if r.Contains != "" && !strings.Contains(msg, r.Contains) {
+		return false
+	}
+	return r.Regexp == nil || r.Regexp.MatchString(msg)
+}
+
+// WithSeverityRules returns an Option that overrides the severity of entries with the level of the first rule
+// matching the message, unless the severity is set by Severity,
+// e.g. to normalize the entries of a third-party library logging "deadline exceeded" at ERROR.
+// Like Severity, the entry must still be enabled at the level it's logged at.
+//
+// The rules are evaluated for every entry, and a regular expression is much slower than Contains,
+// so put the rules with Contains first.
+func WithSeverityRules(rules []SeverityRule) Option {
+	rules = append([]SeverityRule(nil), rules...)
+	return wrapCore(func(core zapcore.Core) zapcore.Core {
+		return &severityRulesCore{Core: core, rules: rules}
+	})
+}
+
+type severityRulesCore struct {
+	zapcore.Core
+	rules []SeverityRule
+}
+
+func (c *severityRulesCore) With(fields []zapcore.Field) zapcore.Core {
+	return &severityRulesCore{
+		Core:  c.Core.With(fields),
+		rules: c.rules,
+	}
+}
+
+func (c *severityRulesCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return checkRewrite(c.Core, ent, ce, c)
+}
+
+func (c *severityRulesCore) rewrite(ent *zapcore.Entry, fields []zapcore.Field) ([]zapcore.Field, bool) {
+	fields = flatten(fields)
+	if _, ok := severityOf(fields); ok {
+		return fields, true
+	}
+	for _, r := range c.rules {
+		if r.match(ent.Message) {
+			ent.Level = r.Level
+			break
+		}
+	}
+	return fields, true
+}
+
+// WithCriticalHook returns an Option that calls hook synchronously when an entry at CRITICAL or above
+// (zapcore.DPanicLevel, zapcore.PanicLevel and zapcore.FatalLevel) is written,
+// including entries overridden with Severity, WithErrorSeverityClassifier or WithSeverityRules
+// wherever the options are, e.g. to page someone independently of log-based alerts.
+//
+// hook is called on the logging path, so it must not block; hand the entry off to a goroutine
+// or a bounded queue for slow work.
+func WithCriticalHook(hook func(zapcore.Entry)) Option {
+	return optionFunc(func(s *settings) {
+		s.criticalHooks = append(s.criticalHooks, hook)
+	})
+}
+
+// criticalHookCore calls the hooks of WithCriticalHook.
+// It's wrapped by reservedCore, so the entries have the final level.
+type criticalHookCore struct {
+	zapcore.Core
+	hooks []func(zapcore.Entry)
+}
+
+func (c *criticalHookCore) With(fields []zapcore.Field) zapcore.Core {
+	return &criticalHookCore{
+		Core:  c.Core.With(fields),
+		hooks: c.hooks,
+	}
+}
+
+func (c *criticalHookCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return checkRewrite(c.Core, ent, ce, c)
+}
+
+func (c *criticalHookCore) rewrite(ent *zapcore.Entry, fields []zapcore.Field) ([]zapcore.Field, bool) {
+	if ent.Level >= zapcore.DPanicLevel {
+		for _, hook := range c.hooks {
+			hook(*ent)
+		}
+	}
+	return fields, true
+}
@@ -0,0 +1,335 @@
+package zapcloudlogging
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestLevelFromEnv(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  zapcore.Level
+	}{
+		{name: "unset", want: zapcore.InfoLevel},
+		{name: "WARNING", value: "WARNING", want: zapcore.WarnLevel},
+		{name: "lowercase", value: "error", want: zapcore.ErrorLevel},
+		{name: "spaces", value: " CRITICAL\n", want: zapcore.DPanicLevel},
+		{name: "NOTICE", value: "NOTICE", want: zapcore.InfoLevel},
+		{name: "DEFAULT", value: "DEFAULT", want: zapcore.DebugLevel},
+		{name: "unknown", value: "VERBOSE", want: zapcore.InfoLevel},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(MinSeverityEnv, tt.value)
+			if got := LevelFromEnv().Level(); got != tt.want {
+				t.Errorf("LevelFromEnv() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSeverityOf(t *testing.T) {
+	tests := []struct {
+		level zapcore.Level
+		want  string
+	}{
+		{level: zapcore.DebugLevel, want: "DEBUG"},
+		{level: zapcore.InfoLevel, want: "INFO"},
+		{level: zapcore.WarnLevel, want: "WARNING"},
+		{level: zapcore.ErrorLevel, want: "ERROR"},
+		{level: zapcore.DPanicLevel, want: "CRITICAL"},
+		{level: zapcore.PanicLevel, want: "ALERT"},
+		{level: zapcore.FatalLevel, want: "EMERGENCY"},
+		{level: zapcore.Level(42), want: "DEFAULT"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := SeverityOf(tt.level); got != tt.want {
+				t.Errorf("SeverityOf(%v) = %q, want %q", tt.level, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLevelOf(t *testing.T) {
+	tests := []struct {
+		severity string
+		want     zapcore.Level
+		wantOK   bool
+	}{
+		{severity: "DEBUG", want: zapcore.DebugLevel, wantOK: true},
+		{severity: "INFO", want: zapcore.InfoLevel, wantOK: true},
+		{severity: "warning", want: zapcore.WarnLevel, wantOK: true},
+		{severity: "Error", want: zapcore.ErrorLevel, wantOK: true},
+		{severity: "CRITICAL", want: zapcore.DPanicLevel, wantOK: true},
+		{severity: "ALERT", want: zapcore.PanicLevel, wantOK: true},
+		{severity: "EMERGENCY", want: zapcore.FatalLevel, wantOK: true},
+		{severity: "NOTICE", want: zapcore.InfoLevel, wantOK: true},
+		{severity: "DEFAULT", want: zapcore.DebugLevel, wantOK: true},
+		{severity: "WARN"},
+		{severity: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.severity, func(t *testing.T) {
+			got, ok := LevelOf(tt.severity)
+			if ok != tt.wantOK || ok && got != tt.want {
+				t.Errorf("LevelOf(%q) = %v, %v, want %v, %v", tt.severity, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestSeverity(t *testing.T) {
+	tests := []struct {
+		name string
+		log  func(logger *zap.Logger)
+		want string
+	}{
+		{name: "none", log: func(logger *zap.Logger) { logger.Info("msg") }, want: "INFO"},
+		{
+			name: "raised",
+			log:  func(logger *zap.Logger) { logger.Info("msg", Severity(zapcore.ErrorLevel)) },
+			want: "ERROR",
+		},
+		{
+			name: "lowered",
+			log:  func(logger *zap.Logger) { logger.Error("msg", Severity(zapcore.DebugLevel)) },
+			want: "DEBUG",
+		},
+		{
+			name: "last wins",
+			log: func(logger *zap.Logger) {
+				logger.Info("msg", Severity(zapcore.WarnLevel), Severity(zapcore.ErrorLevel))
+			},
+			want: "ERROR",
+		},
+		{
+			name: "panic",
+			log:  func(logger *zap.Logger) { logger.Info("msg", Severity(zapcore.PanicLevel)) },
+			want: "ALERT",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, sink := newTestLogger(t)
+			tt.log(logger)
+
+			assertJSON(t, SeverityKey, sink.entries(t)[0][SeverityKey], tt.want)
+		})
+	}
+}
+
+func TestWithErrorSeverityClassifier(t *testing.T) {
+	errNotFound := errors.New("not found")
+	classifier := WithErrorSeverityClassifier(func(err error) (zapcore.Level, bool) {
+		return zapcore.WarnLevel, errors.Is(err, errNotFound)
+	})
+
+	tests := []struct {
+		name   string
+		with   []zap.Field
+		fields []zap.Field
+		want   string
+	}{
+		{name: "no error", want: "ERROR"},
+		{name: "with", with: []zap.Field{zap.Error(errNotFound)}, want: "WARNING"},
+		{name: "with grouped", with: []zap.Field{group(zap.Error(errNotFound))}, want: "WARNING"},
+		{
+			name:   "entry over with",
+			with:   []zap.Field{zap.Error(errNotFound)},
+			fields: []zap.Field{zap.Error(errors.New("other"))},
+			want:   "ERROR",
+		},
+		{
+			name:   "severity over with",
+			with:   []zap.Field{zap.Error(errNotFound)},
+			fields: []zap.Field{Severity(zapcore.DPanicLevel)},
+			want:   "CRITICAL",
+		},
+		{name: "classified", fields: []zap.Field{zap.Error(errNotFound)}, want: "WARNING"},
+		{name: "wrapped", fields: []zap.Field{zap.Error(fmt.Errorf("get: %w", errNotFound))}, want: "WARNING"},
+		{name: "not classified", fields: []zap.Field{zap.Error(errors.New("other"))}, want: "ERROR"},
+		{name: "named error", fields: []zap.Field{zap.NamedError("cause", errNotFound)}, want: "WARNING"},
+		{
+			name:   "severity wins",
+			fields: []zap.Field{zap.Error(errNotFound), Severity(zapcore.DPanicLevel)},
+			want:   "CRITICAL",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, sink := newTestLogger(t, classifier)
+			logger.With(tt.with...).Error("msg", tt.fields...)
+
+			assertJSON(t, SeverityKey, sink.entries(t)[0][SeverityKey], tt.want)
+		})
+	}
+}
+
+func TestWithCriticalHook(t *testing.T) {
+	errOutage := errors.New("outage")
+	rules := WithSeverityRules([]SeverityRule{
+		{Contains: "disk full", Level: zapcore.DPanicLevel},
+		{Contains: "known issue", Level: zapcore.WarnLevel},
+	})
+	classifier := WithErrorSeverityClassifier(func(err error) (zapcore.Level, bool) {
+		return zapcore.DPanicLevel, errors.Is(err, errOutage)
+	})
+
+	tests := []struct {
+		name string
+		opts []Option
+		log  func(logger *zap.Logger)
+		want []zapcore.Level
+	}{
+		{
+			name: "error",
+			log:  func(logger *zap.Logger) { logger.Error("failed") },
+		},
+		{
+			name: "dpanic",
+			log:  func(logger *zap.Logger) { logger.DPanic("failed") },
+			want: []zapcore.Level{zapcore.DPanicLevel},
+		},
+		{
+			name: "severity field",
+			log:  func(logger *zap.Logger) { logger.Info("failed", Severity(zapcore.DPanicLevel)) },
+			want: []zapcore.Level{zapcore.DPanicLevel},
+		},
+		{
+			name: "severity field lowered",
+			log:  func(logger *zap.Logger) { logger.DPanic("failed", Severity(zapcore.ErrorLevel)) },
+		},
+		{
+			name: "rules raised",
+			opts: []Option{rules},
+			log:  func(logger *zap.Logger) { logger.Error("disk full") },
+			want: []zapcore.Level{zapcore.DPanicLevel},
+		},
+		{
+			name: "rules lowered",
+			opts: []Option{rules},
+			log:  func(logger *zap.Logger) { logger.DPanic("known issue") },
+		},
+		{
+			name: "classifier raised",
+			opts: []Option{classifier},
+			log:  func(logger *zap.Logger) { logger.Error("failed", zap.Error(errOutage)) },
+			want: []zapcore.Level{zapcore.DPanicLevel},
+		},
+		{
+			name: "classifier not matched",
+			opts: []Option{classifier},
+			log:  func(logger *zap.Logger) { logger.Error("failed", zap.Error(errors.New("other"))) },
+		},
+	}
+	for _, tt := range tests {
+		for _, order := range []string{"hook first", "hook last"} {
+			t.Run(tt.name+"/"+order, func(t *testing.T) {
+				var got []zapcore.Level
+				hook := WithCriticalHook(func(ent zapcore.Entry) {
+					got = append(got, ent.Level)
+				})
+				opts := append([]Option{hook}, tt.opts...)
+				if order == "hook last" {
+					opts = append(append([]Option(nil), tt.opts...), hook)
+				}
+				logger, sink := newTestLogger(t, opts...)
+
+				tt.log(logger)
+
+				if n := len(sink.entries(t)); n != 1 {
+					t.Fatalf("got %d entries, want 1", n)
+				}
+				if len(got) != len(tt.want) {
+					t.Fatalf("hook called with %v, want %v", got, tt.want)
+				}
+				for i := range got {
+					if got[i] != tt.want[i] {
+						t.Errorf("hook called with %v, want %v", got, tt.want)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestWithSeverityRules(t *testing.T) {
+	rules := WithSeverityRules([]SeverityRule{
+		{Contains: "deadline exceeded", Level: zapcore.WarnLevel},
+		{Regexp: regexp.MustCompile(`^retry \d+$`), Level: zapcore.DebugLevel},
+		{Contains: "cache", Regexp: regexp.MustCompile(`miss$`), Level: zapcore.InfoLevel},
+		{Contains: "deadline", Level: zapcore.DPanicLevel},
+		{Level: zapcore.FatalLevel},
+	})
+
+	tests := []struct {
+		name   string
+		msg    string
+		fields []zap.Field
+		want   string
+	}{
+		{name: "no match", msg: "failed", want: "ERROR"},
+		{name: "contains", msg: "rpc: deadline exceeded", want: "WARNING"},
+		{name: "regexp", msg: "retry 3", want: "DEBUG"},
+		{name: "regexp not matched", msg: "retry 3 times", want: "ERROR"},
+		{name: "both", msg: "cache miss", want: "INFO"},
+		{name: "both partly matched", msg: "cache hit", want: "ERROR"},
+		{name: "first match", msg: "deadline exceeded", want: "WARNING"},
+		{name: "later rule", msg: "deadline soon", want: "CRITICAL"},
+		{
+			name:   "severity wins",
+			msg:    "deadline exceeded",
+			fields: []zap.Field{Severity(zapcore.ErrorLevel)},
+			want:   "ERROR",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, sink := newTestLogger(t, rules)
+			logger.Error(tt.msg, tt.fields...)
+
+			assertJSON(t, SeverityKey, sink.entries(t)[0][SeverityKey], tt.want)
+		})
+	}
+}
+
+func TestWithSeverityNumberField(t *testing.T) {
+	tests := []struct {
+		name string
+		log  func(logger *zap.Logger)
+		want any
+	}{
+		{name: "info", log: func(logger *zap.Logger) { logger.Info("msg") }, want: 200},
+		{name: "warning", log: func(logger *zap.Logger) { logger.Warn("msg") }, want: 400},
+		{name: "critical", log: func(logger *zap.Logger) { logger.DPanic("msg") }, want: 600},
+		{
+			name: "severity field",
+			log:  func(logger *zap.Logger) { logger.Info("msg", Severity(zapcore.ErrorLevel)) },
+			want: 500,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, sink := newTestLogger(t, WithSeverityNumberField("severity_number"))
+			tt.log(logger)
+
+			assertJSON(t, "severity_number", sink.entries(t)[0]["severity_number"], tt.want)
+		})
+	}
+
+	t.Run("unset", func(t *testing.T) {
+		logger, sink := newTestLogger(t)
+		logger.Info("msg")
+
+		if v, ok := sink.entries(t)[0]["severity_number"]; ok {
+			t.Errorf("severity_number = %v, want none", v)
+		}
+	})
+}
@@ -0,0 +1,214 @@
+package zapcloudlogging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// A MetadataClient gets values from the metadata server.
+//
+// https://cloud.google.com/compute/docs/metadata/overview
+type MetadataClient interface {
+	// Get returns the value of path relative to computeMetadata/v1/, e.g. "project/project-id".
+	Get(ctx context.Context, path string) (string, error)
+}
+
+const defaultMetadataHost = "metadata.google.internal"
+
+type metadataServerClient struct {
+	host   string
+	client *http.Client
+}
+
+// NewMetadataClient returns a MetadataClient that requests the metadata server.
+// The host can be overridden with the GCE_METADATA_HOST environment variable.
+func NewMetadataClient() MetadataClient {
+	host := os.Getenv("GCE_METADATA_HOST")
+	if host == "" {
+		host = defaultMetadataHost
+	}
+	return &metadataServerClient{
+		host:   host,
+		client: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+func (c *metadataServerClient) Get(ctx context.Context, path string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+c.host+"/computeMetadata/v1/"+path, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		return "", err
+	}
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("zapcloudlogging: metadata %s: %s", path, res.Status)
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+type detectOptions struct {
+	client MetadataClient
+}
+
+// A DetectOption configures DetectProjectID and DetectResource.
+type DetectOption func(*detectOptions)
+
+// WithMetadataClient sets the MetadataClient used to detect values, e.g. a fake one or one caching values.
+// By default, the client returned by NewMetadataClient is used.
+func WithMetadataClient(c MetadataClient) DetectOption {
+	return func(o *detectOptions) {
+		o.client = c
+	}
+}
+
+func newDetectOptions(opts []DetectOption) *detectOptions {
+	var o detectOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.client == nil {
+		o.client = NewMetadataClient()
+	}
+	return &o
+}
+
+// DetectProjectID returns the ID of the project the program is running in.
+// The GOOGLE_CLOUD_PROJECT environment variable is used if it's set,
+// otherwise the ID is got from the metadata server.
+func DetectProjectID(ctx context.Context, opts ...DetectOption) (string, error) {
+	return newDetectOptions(opts).projectID(ctx)
+}
+
+func (o *detectOptions) projectID(ctx context.Context) (string, error) {
+	if id := os.Getenv("GOOGLE_CLOUD_PROJECT"); id != "" {
+		return id, nil
+	}
+	return o.client.Get(ctx, "project/project-id")
+}
+
+// MonitoredResource is the resource that produces log entries.
+//
+// https://cloud.google.com/logging/docs/reference/v2/rest/v2/MonitoredResource
+type MonitoredResource struct {
+	Type   string
+	Labels map[string]string
+}
+
+// DetectResource returns the monitored resource the program is running on,
+// determined from the environment variables set by each platform and the metadata server.
+// It detects Cloud Run, Cloud Functions, App Engine, GKE and Compute Engine.
+// On GKE, the namespace and the container names are read from the NAMESPACE_NAME and
+// CONTAINER_NAME environment variables, which should be set with the Downward API.
+//
+// https://cloud.google.com/logging/docs/api/v2/resource-list
+func DetectResource(ctx context.Context, opts ...DetectOption) (*MonitoredResource, error) {
+	o := newDetectOptions(opts)
+
+	projectID, err := o.projectID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	labels := map[string]string{"project_id": projectID}
+
+	var typ string
+	var paths map[string]string
+	switch {
+	case os.Getenv("FUNCTION_TARGET") != "" && os.Getenv("K_SERVICE") != "":
+		typ = "cloud_function"
+		labels["function_name"] = os.Getenv("K_SERVICE")
+		paths = map[string]string{"region": "instance/region"}
+	case os.Getenv("K_CONFIGURATION") != "":
+		typ = "cloud_run_revision"
+		labels["service_name"] = os.Getenv("K_SERVICE")
+		labels["revision_name"] = os.Getenv("K_REVISION")
+		labels["configuration_name"] = os.Getenv("K_CONFIGURATION")
+		paths = map[string]string{"location": "instance/region"}
+	case os.Getenv("GAE_SERVICE") != "":
+		typ = "gae_app"
+		labels["module_id"] = os.Getenv("GAE_SERVICE")
+		labels["version_id"] = os.Getenv("GAE_VERSION")
+		paths = map[string]string{"zone": "instance/zone"}
+	case os.Getenv("KUBERNETES_SERVICE_HOST") != "":
+		typ = "k8s_container"
+		labels["namespace_name"] = os.Getenv("NAMESPACE_NAME")
+		labels["pod_name"] = os.Getenv("HOSTNAME")
+		labels["container_name"] = os.Getenv("CONTAINER_NAME")
+		paths = map[string]string{
+			"location":     "instance/attributes/cluster-location",
+			"cluster_name": "instance/attributes/cluster-name",
+		}
+	default:
+		typ = "gce_instance"
+		paths = map[string]string{
+			"instance_id": "instance/id",
+			"zone":        "instance/zone",
+		}
+	}
+
+	for key, path := range paths {
+		v, err := o.client.Get(ctx, path)
+		if err != nil {
+			return nil, err
+		}
+		// Regions and zones are returned like "projects/123456789/zones/us-central1-a".
+		if i := strings.LastIndexByte(v, '/'); i >= 0 {
+			v = v[i+1:]
+		}
+		labels[key] = v
+	}
+
+	return &MonitoredResource{
+		Type:   typ,
+		Labels: labels,
+	}, nil
+}
+
+// envResourceLabels are the environment variables read by EnvResourceLabels and their label keys.
+var envResourceLabels = []struct {
+	env, key string
+}{
+	// Cloud Run and Cloud Functions
+	{"K_SERVICE", "service_name"},
+	{"K_REVISION", "revision_name"},
+	{"K_CONFIGURATION", "configuration_name"},
+	{"FUNCTION_TARGET", "function_target"},
+	// App Engine
+	{"GAE_SERVICE", "module_id"},
+	{"GAE_VERSION", "version_id"},
+	// GKE, set with the Downward API
+	{"NAMESPACE_NAME", "namespace_name"},
+	{"POD_NAME", "pod_name"},
+	{"CONTAINER_NAME", "container_name"},
+}
+
+// EnvResourceLabels returns the labels of the resource the program is running on,
+// read from the environment variables set by Cloud Run, Cloud Functions and App Engine,
+// and the NAMESPACE_NAME, POD_NAME and CONTAINER_NAME environment variables on GKE.
+// The keys are the labels of the monitored resources, e.g. service_name for K_SERVICE.
+// Only the variables that are set are returned. The labels can be set with Labels.
+//
+// Unlike DetectResource, it doesn't request the metadata server.
+func EnvResourceLabels() map[string]string {
+	labels := make(map[string]string)
+	for _, l := range envResourceLabels {
+		if v := os.Getenv(l.env); v != "" {
+			labels[l.key] = v
+		}
+	}
+	return labels
+}
@@ -0,0 +1,248 @@
+package zapcloudlogging
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakeMetadataClient is a MetadataClient returning the values of a map.
+type fakeMetadataClient map[string]string
+
+var errNotFound = errors.New("not found")
+
+func (c fakeMetadataClient) Get(ctx context.Context, path string) (string, error) {
+	v, ok := c[path]
+	if !ok {
+		return "", errNotFound
+	}
+	return v, nil
+}
+
+// testMetadata is the metadata of the server the tests run on.
+var testMetadata = fakeMetadataClient{
+	"project/project-id":                   "metadata-project",
+	"instance/id":                          "1234567890",
+	"instance/zone":                        "projects/123/zones/asia-northeast1-a",
+	"instance/region":                      "projects/123/regions/asia-northeast1",
+	"instance/attributes/cluster-location": "asia-northeast1",
+	"instance/attributes/cluster-name":     "cluster",
+}
+
+// resourceEnv are the environment variables read to detect the resource.
+var resourceEnv = []string{
+	"GOOGLE_CLOUD_PROJECT",
+	"FUNCTION_TARGET", "K_SERVICE", "K_REVISION", "K_CONFIGURATION",
+	"GAE_SERVICE", "GAE_VERSION",
+	"KUBERNETES_SERVICE_HOST", "NAMESPACE_NAME", "HOSTNAME", "POD_NAME", "CONTAINER_NAME",
+}
+
+// setResourceEnv clears the environment variables of resourceEnv and sets env.
+func setResourceEnv(t *testing.T, env map[string]string) {
+	for _, key := range resourceEnv {
+		t.Setenv(key, env[key])
+	}
+}
+
+func TestDetectProjectID(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		client  MetadataClient
+		want    string
+		wantErr error
+	}{
+		{name: "env", env: map[string]string{"GOOGLE_CLOUD_PROJECT": "env-project"}, client: testMetadata, want: "env-project"},
+		{name: "metadata", client: testMetadata, want: "metadata-project"},
+		{name: "error", client: fakeMetadataClient{}, wantErr: errNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setResourceEnv(t, tt.env)
+			got, err := DetectProjectID(context.Background(), WithMetadataClient(tt.client))
+			if got != tt.want || !errors.Is(err, tt.wantErr) {
+				t.Errorf("DetectProjectID() = %q, %v, want %q, %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDetectResource(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		client  MetadataClient
+		want    *MonitoredResource
+		wantErr error
+	}{
+		{
+			name:   "Compute Engine",
+			client: testMetadata,
+			want: &MonitoredResource{Type: "gce_instance", Labels: map[string]string{
+				"project_id":  "metadata-project",
+				"instance_id": "1234567890",
+				"zone":        "asia-northeast1-a",
+			}},
+		},
+		{
+			name: "Cloud Run",
+			env: map[string]string{
+				"GOOGLE_CLOUD_PROJECT": "env-project",
+				"K_SERVICE":            "api",
+				"K_REVISION":           "api-00001",
+				"K_CONFIGURATION":      "api",
+			},
+			client: testMetadata,
+			want: &MonitoredResource{Type: "cloud_run_revision", Labels: map[string]string{
+				"project_id":         "env-project",
+				"service_name":       "api",
+				"revision_name":      "api-00001",
+				"configuration_name": "api",
+				"location":           "asia-northeast1",
+			}},
+		},
+		{
+			name: "Cloud Functions",
+			env: map[string]string{
+				"FUNCTION_TARGET": "Handle",
+				"K_SERVICE":       "handler",
+				"K_REVISION":      "handler-00001",
+				"K_CONFIGURATION": "handler",
+			},
+			client: testMetadata,
+			want: &MonitoredResource{Type: "cloud_function", Labels: map[string]string{
+				"project_id":    "metadata-project",
+				"function_name": "handler",
+				"region":        "asia-northeast1",
+			}},
+		},
+		{
+			name:   "App Engine",
+			env:    map[string]string{"GAE_SERVICE": "default", "GAE_VERSION": "v1"},
+			client: testMetadata,
+			want: &MonitoredResource{Type: "gae_app", Labels: map[string]string{
+				"project_id": "metadata-project",
+				"module_id":  "default",
+				"version_id": "v1",
+				"zone":       "asia-northeast1-a",
+			}},
+		},
+		{
+			name: "GKE",
+			env: map[string]string{
+				"KUBERNETES_SERVICE_HOST": "10.0.0.1",
+				"NAMESPACE_NAME":          "prod",
+				"HOSTNAME":                "api-7d9f",
+				"CONTAINER_NAME":          "app",
+			},
+			client: testMetadata,
+			want: &MonitoredResource{Type: "k8s_container", Labels: map[string]string{
+				"project_id":     "metadata-project",
+				"namespace_name": "prod",
+				"pod_name":       "api-7d9f",
+				"container_name": "app",
+				"location":       "asia-northeast1",
+				"cluster_name":   "cluster",
+			}},
+		},
+		{
+			name:    "metadata error",
+			env:     map[string]string{"GOOGLE_CLOUD_PROJECT": "env-project"},
+			client:  fakeMetadataClient{},
+			wantErr: errNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setResourceEnv(t, tt.env)
+			got, err := DetectResource(context.Background(), WithMetadataClient(tt.client))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("DetectResource() error = %v, want %v", err, tt.wantErr)
+			}
+			assertJSON(t, "resource", got, tt.want)
+		})
+	}
+}
+
+func TestNewMetadataClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			http.Error(w, "missing Metadata-Flavor", http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/computeMetadata/v1/project/project-id":
+			w.Write([]byte("my-project\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	t.Setenv("GCE_METADATA_HOST", strings.TrimPrefix(srv.URL, "http://"))
+
+	tests := []struct {
+		name    string
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{name: "found", path: "project/project-id", want: "my-project"},
+		{name: "not found", path: "instance/id", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewMetadataClient().Get(context.Background(), tt.path)
+			if got != tt.want || (err != nil) != tt.wantErr {
+				t.Errorf("Get(%q) = %q, %v, want %q, error %v", tt.path, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEnvResourceLabels(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want map[string]string
+	}{
+		{name: "none", want: map[string]string{}},
+		{
+			name: "Cloud Run",
+			env:  map[string]string{"K_SERVICE": "api", "K_REVISION": "api-00001", "K_CONFIGURATION": "api"},
+			want: map[string]string{"service_name": "api", "revision_name": "api-00001", "configuration_name": "api"},
+		},
+		{
+			name: "Cloud Functions",
+			env:  map[string]string{"K_SERVICE": "fn", "FUNCTION_TARGET": "Handle"},
+			want: map[string]string{"service_name": "fn", "function_target": "Handle"},
+		},
+		{
+			name: "App Engine",
+			env:  map[string]string{"GAE_SERVICE": "default", "GAE_VERSION": "v1"},
+			want: map[string]string{"module_id": "default", "version_id": "v1"},
+		},
+		{
+			name: "GKE",
+			env: map[string]string{
+				"KUBERNETES_SERVICE_HOST": "10.0.0.1", "HOSTNAME": "api-7d9f",
+				"NAMESPACE_NAME": "prod", "POD_NAME": "api-7d9f", "CONTAINER_NAME": "app",
+			},
+			want: map[string]string{"namespace_name": "prod", "pod_name": "api-7d9f", "container_name": "app"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setResourceEnv(t, tt.env)
+
+			got := encodeFields(t, Labels(EnvResourceLabels()))
+			want := any(tt.want)
+			if len(tt.want) == 0 {
+				want = nil
+			}
+			assertJSON(t, "labels", got[LabelsKey], want)
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package zapcloudlogging
+
+import (
+	"os"
+	"reflect"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// settings holds the settings applied by Options.
+type settings struct {
+	encoder []EncoderOption
+	config  []func(*zap.Config)
+	logger  []zap.Option
+	// sortKeys sorts the keys of the objects of this package.
+	sortKeys bool
+	// labels are set to every entry, like the labels added by With.
+	labels labels
+	// defaultLabels are the labels set to entries missing them.
+	defaultLabels labels
+	// severityNumberKey is the key of the severity number, if it's not empty.
+	severityNumberKey string
+	// forceJSON encodes the entries as JSON whatever the config sets.
+	forceJSON bool
+	// omitEmptyMessage encodes the JSON entries with Encoding.
+	omitEmptyMessage bool
+	// criticalHooks are called for the entries at CRITICAL or above.
+	criticalHooks []func(zapcore.Entry)
+	// samplingExemptions samples the entries with samplerCore, so that Always exempts them.
+	samplingExemptions bool
+}
+
+// An Option configures a logger built by NewProduction or NewDevelopment.
+// An Option can modify both the zap.Config the logger is built from and the built *zap.Logger.
+// Every EncoderOption is also an Option.
+type Option interface {
+	apply(*settings)
+}
+
+type optionFunc func(*settings)
+
+func (f optionFunc) apply(s *settings) {
+	f(s)
+}
+
+func (o EncoderOption) apply(s *settings) {
+	s.encoder = append(s.encoder, o)
+}
+
+// WithConfig returns an Option that modifies the zap.Config with f before the logger is built.
+func WithConfig(f func(*zap.Config)) Option {
+	return optionFunc(func(s *settings) {
+		s.config = append(s.config, f)
+	})
+}
+
+// WithZapOptions returns an Option that applies opts to the built logger.
+func WithZapOptions(opts ...zap.Option) Option {
+	return optionFunc(func(s *settings) {
+		s.logger = append(s.logger, opts...)
+	})
+}
+
+// WithInitialFields returns an Option that adds fields to every entry with zap.Config.InitialFields,
+// e.g. the env and region of the deployment.
+// The fields with the reserved keys, see IsReservedKey, are skipped
+// so that they don't collide with the fields encoded by this package; use Labels for labels.
+func WithInitialFields(fields map[string]any) Option {
+	return WithConfig(func(cfg *zap.Config) {
+		for k, v := range fields {
+			if IsReservedKey(k) {
+				continue
+			}
+			if cfg.InitialFields == nil {
+				cfg.InitialFields = make(map[string]interface{}, len(fields))
+			}
+			cfg.InitialFields[k] = v
+		}
+	})
+}
+
+// WithLevelRef returns an Option that makes the logger enabled at al, instead of a level of its own,
+// so that the loggers built with the same al, e.g. one per subsystem, are adjusted together,
+// e.g. by serving al at a /loglevel endpoint.
+func WithLevelRef(al zap.AtomicLevel) Option {
+	return WithConfig(func(cfg *zap.Config) {
+		cfg.Level = al
+	})
+}
+
+// WithoutStacktrace returns an Option that never logs stack traces, e.g. when they're collected elsewhere,
+// even if they're captured by zap.AddStacktrace.
+func WithoutStacktrace() Option {
+	return WithConfig(func(cfg *zap.Config) {
+		cfg.DisableStacktrace = true
+		cfg.EncoderConfig.StacktraceKey = zapcore.OmitKey
+	})
+}
+
+// WithForceJSON returns an Option that encodes the entries as JSON and the severity without color,
+// even if another Option sets a console encoding or a color level encoder with WithConfig,
+// e.g. for CI environments parsing the development logs.
+// Without it, the color level encoders are still replaced with the encoders without color
+// when the NO_COLOR environment variable is set or TERM is "dumb".
+func WithForceJSON() Option {
+	return optionFunc(func(s *settings) {
+		s.forceJSON = true
+	})
+}
+
+// noColor reports whether the output must not be colored, by NO_COLOR or TERM=dumb.
+//
+// https://no-color.org/
+func noColor() bool {
+	return os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb"
+}
+
+// withoutColor returns the level encoder of zapcore without color for the color level encoders of zapcore,
+// and enc itself for the others.
+func withoutColor(enc zapcore.LevelEncoder) zapcore.LevelEncoder {
+	if enc == nil {
+		return nil
+	}
+	switch reflect.ValueOf(enc).Pointer() {
+	case reflect.ValueOf(zapcore.CapitalColorLevelEncoder).Pointer():
+		return zapcore.CapitalLevelEncoder
+	case reflect.ValueOf(zapcore.LowercaseColorLevelEncoder).Pointer():
+		return zapcore.LowercaseLevelEncoder
+	}
+	return enc
+}
+
+// WithSortedKeys returns an Option that encodes the merged labels ordered by key,
+// e.g. for the comparison with golden files. By default, they're ordered as they were set.
+// The other objects of this package are always encoded in a fixed order.
+func WithSortedKeys() Option {
+	return optionFunc(func(s *settings) {
+		s.sortKeys = true
+	})
+}
+
+// wrapCore returns an Option that wraps the core of the built logger with f.
+func wrapCore(f func(zapcore.Core) zapcore.Core) Option {
+	return WithZapOptions(zap.WrapCore(f))
+}
+
+// build builds a logger from cfg with opts.
+// newEncoderConfig creates the encoder config when opts contain EncoderOptions.
+func build(cfg zap.Config, newEncoderConfig func(...EncoderOption) zapcore.EncoderConfig, opts []Option) (*zap.Logger, error) {
+	var s settings
+	for _, opt := range opts {
+		opt.apply(&s)
+	}
+
+	if len(s.encoder) > 0 {
+		cfg.EncoderConfig = newEncoderConfig(s.encoder...)
+	}
+	for _, f := range s.config {
+		f(&cfg)
+	}
+	if s.forceJSON {
+		cfg.Encoding = "json"
+		cfg.EncoderConfig.EncodeLevel = severityEncoder
+	} else if noColor() {
+		cfg.EncoderConfig.EncodeLevel = withoutColor(cfg.EncoderConfig.EncodeLevel)
+	}
+	if s.omitEmptyMessage && cfg.Encoding == "json" {
+		cfg.Encoding = Encoding
+	}
+
+	// WrapCore is applied first, so the cores of the options wrap it.
+	// It merges the labels and overrides the severity last,
+	// so it sets the default labels, sorts them and logs the severity number,
+	// and the hooks of WithCriticalHook inside it see the final severity.
+	// With WithSamplingExemptions, the entries are sampled by samplerCore instead, so that Always exempts them.
+	var sampling *zap.SamplingConfig
+	if s.samplingExemptions {
+		sampling = cfg.Sampling
+		cfg.Sampling = nil
+	}
+
+	wrap := zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		if sampling != nil {
+			core = newSamplerCore(core, sampling)
+		}
+		if len(s.criticalHooks) > 0 {
+			core = &criticalHookCore{Core: core, hooks: s.criticalHooks}
+		}
+		return &reservedCore{
+			Core:              core,
+			labels:            s.labels,
+			defaultLabels:     s.defaultLabels,
+			sortLabels:        s.sortKeys,
+			severityNumberKey: s.severityNumberKey,
+		}
+	})
+	return cfg.Build(append([]zap.Option{wrap}, s.logger...)...)
+}
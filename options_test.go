@@ -0,0 +1,253 @@
+package zapcloudlogging
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestNewProduction(t *testing.T) {
+	tests := []struct {
+		name string
+		new  func(opts ...Option) (*zap.Logger, error)
+		want zapcore.Level
+	}{
+		{name: "production", new: NewProduction, want: zapcore.InfoLevel},
+		{name: "development", new: NewDevelopment, want: zapcore.DebugLevel},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, err := tt.new(WithConfig(func(cfg *zap.Config) {
+				cfg.OutputPaths = nil
+			}))
+			if err != nil {
+				t.Fatalf("got error %v", err)
+			}
+			if core := logger.Core(); !core.Enabled(tt.want) || core.Enabled(tt.want-1) {
+				t.Errorf("logger isn't enabled at %v", tt.want)
+			}
+		})
+	}
+}
+
+func TestOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		want map[string]any
+	}{
+		{
+			name: "none",
+			want: map[string]any{SeverityKey: "INFO", MessageKey: "msg", "elapsed": 1000},
+		},
+		{
+			name: "encoder option",
+			opts: []Option{WithDurationEncoder(zapcore.StringDurationEncoder)},
+			want: map[string]any{SeverityKey: "INFO", MessageKey: "msg", "elapsed": "1s"},
+		},
+		{
+			name: "WithConfig",
+			opts: []Option{WithConfig(func(cfg *zap.Config) {
+				cfg.InitialFields = map[string]any{"env": "prod"}
+			})},
+			want: map[string]any{SeverityKey: "INFO", MessageKey: "msg", "env": "prod", "elapsed": 1000},
+		},
+		{
+			name: "WithZapOptions",
+			opts: []Option{WithZapOptions(zap.Fields(Label("env", "prod")))},
+			want: map[string]any{
+				SeverityKey: "INFO",
+				MessageKey:  "msg",
+				LabelsKey:   map[string]any{"env": "prod"},
+				"elapsed":   1000,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, sink := newTestLogger(t, tt.opts...)
+			logger.Info("msg", zap.Duration("elapsed", time.Second))
+
+			assertJSON(t, "entries", sink.entries(t), []map[string]any{tt.want})
+		})
+	}
+}
+
+// withColorConsole is an Option that encodes the entries with the console encoder and a color level encoder.
+var withColorConsole = WithConfig(func(cfg *zap.Config) {
+	cfg.Encoding = "console"
+	cfg.EncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+})
+
+func TestColor(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		opts    []Option
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "color",
+			opts: []Option{withColorConsole},
+			want: "\x1b[34mINFO\x1b[0m\tmsg\n",
+		},
+		{
+			name: "NO_COLOR",
+			env:  map[string]string{"NO_COLOR": "1"},
+			opts: []Option{withColorConsole},
+			want: "INFO\tmsg\n",
+		},
+		{
+			name: "TERM=dumb",
+			env:  map[string]string{"TERM": "dumb"},
+			opts: []Option{withColorConsole},
+			want: "INFO\tmsg\n",
+		},
+		{
+			name: "lowercase",
+			env:  map[string]string{"NO_COLOR": "1"},
+			opts: []Option{withColorConsole, WithConfig(func(cfg *zap.Config) {
+				cfg.EncoderConfig.EncodeLevel = zapcore.LowercaseColorLevelEncoder
+			})},
+			want: "info\tmsg\n",
+		},
+		{
+			name: "WithForceJSON",
+			opts: []Option{WithForceJSON(), withColorConsole},
+			want: `{"severity":"INFO","message":"msg"}` + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("NO_COLOR", "")
+			t.Setenv("TERM", "xterm")
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			logger, sink := buildTestLogger(t, NewDevelopmentConfig(), NewDevelopmentEncoderConfig, tt.opts...)
+			logger.Info("msg")
+			if got := sink.String(); got != tt.want {
+				t.Errorf("output = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithSortedKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		want string
+	}{
+		{name: "as set", want: `{"b":"2","a":"1","c":"3"}`},
+		{name: "sorted", opts: []Option{WithSortedKeys()}, want: `{"a":"1","b":"2","c":"3"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, sink := newTestLogger(t, tt.opts...)
+			logger.With(Label("b", "2")).Info("msg", Label("a", "1"), Label("c", "3"))
+
+			want := `{"severity":"INFO","message":"msg","` + LabelsKey + `":` + tt.want + "}\n"
+			if got := sink.String(); got != want {
+				t.Errorf("output = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestWithoutStacktrace(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		log  func(logger *zap.Logger)
+		want bool
+	}{
+		{name: "error", log: func(logger *zap.Logger) { logger.Error("msg") }, want: true},
+		{name: "info", log: func(logger *zap.Logger) { logger.Info("msg") }},
+		{name: "without", opts: []Option{WithoutStacktrace()}, log: func(logger *zap.Logger) { logger.Error("msg") }},
+		{
+			name: "captured by AddStacktrace",
+			opts: []Option{WithoutStacktrace(), WithZapOptions(zap.AddStacktrace(zapcore.InfoLevel))},
+			log:  func(logger *zap.Logger) { logger.Warn("msg") },
+		},
+		{
+			name: "config after",
+			opts: []Option{WithoutStacktrace(), WithConfig(func(cfg *zap.Config) { cfg.Development = true })},
+			log:  func(logger *zap.Logger) { logger.Warn("msg") },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, sink := newTestLogger(t, tt.opts...)
+			tt.log(logger)
+
+			if _, got := sink.entries(t)[0][StacktraceKey]; got != tt.want {
+				t.Errorf("has %s = %v, want %v", StacktraceKey, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithInitialFields(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields map[string]any
+		want   map[string]any
+	}{
+		{name: "none", want: map[string]any{SeverityKey: "INFO", MessageKey: "msg"}},
+		{
+			name:   "fields",
+			fields: map[string]any{"env": "prod", "replicas": 3},
+			want:   map[string]any{SeverityKey: "INFO", MessageKey: "msg", "env": "prod", "replicas": 3},
+		},
+		{
+			name:   "reserved keys",
+			fields: map[string]any{"env": "prod", SeverityKey: "DEBUG", TraceKey: "trace", LabelsKey: "l"},
+			want:   map[string]any{SeverityKey: "INFO", MessageKey: "msg", "env": "prod"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, sink := newTestLogger(t, WithInitialFields(tt.fields))
+			logger.Info("msg")
+
+			assertJSON(t, "entry", sink.entries(t)[0], tt.want)
+		})
+	}
+}
+
+func TestWithLevelRef(t *testing.T) {
+	al := zap.NewAtomicLevelAt(zapcore.InfoLevel)
+	a, aSink := newTestLogger(t, WithLevelRef(al))
+	b, bSink := buildTestLogger(t, NewDevelopmentConfig(), NewDevelopmentEncoderConfig, WithLevelRef(al))
+
+	tests := []struct {
+		level zapcore.Level
+		log   func(logger *zap.Logger)
+		want  int
+	}{
+		{level: zapcore.InfoLevel, log: func(logger *zap.Logger) { logger.Info("msg") }, want: 1},
+		{level: zapcore.WarnLevel, log: func(logger *zap.Logger) { logger.Info("msg") }, want: 0},
+		{level: zapcore.WarnLevel, log: func(logger *zap.Logger) { logger.Error("msg") }, want: 1},
+		{level: zapcore.DebugLevel, log: func(logger *zap.Logger) { logger.Debug("msg") }, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.level.String(), func(t *testing.T) {
+			al.SetLevel(tt.level)
+			aBefore, bBefore := len(aSink.entries(t)), len(bSink.entries(t))
+			tt.log(a)
+			tt.log(b)
+
+			if got := len(aSink.entries(t)) - aBefore; got != tt.want {
+				t.Errorf("production logger wrote %d entries, want %d", got, tt.want)
+			}
+			if got := len(bSink.entries(t)) - bBefore; got != tt.want {
+				t.Errorf("development logger wrote %d entries, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package zapcloudlogging
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// typeURLPrefix is the prefix of the type URLs of the types known to Cloud Logging.
+const typeURLPrefix = "type.googleapis.com/"
+
+// ProtoPayload returns an empty message and the fields of m as the structured payload, like Payload,
+// with the @type field set to typeURL, e.g. "type.googleapis.com/google.cloud.audit.AuditLog",
+// so that Cloud Logging and the tools reading it recognize the type of the payload.
+// m is marshaled with protojson, so the properties are named and formatted as the JSON mapping of protobuf.
+// If typeURL is empty, it's the type URL of m. If m isn't represented as a JSON object, it's logged as the payload field.
+//
+//	msg, fields := zapcloudlogging.ProtoPayload("", auditLog)
+//	logger.Info(msg, fields...)
+//
+// The entries written to stdout or stderr are still jsonPayload, since only the entries written with
+// the Cloud Logging API can be protoPayload, but the payload has the same shape.
+//
+// If typeURL isn't "type.googleapis.com/" followed by the full name of m, or m can't be marshaled,
+// the error is logged as the error field instead of the payload.
+func ProtoPayload(typeURL string, m proto.Message) (string, []zap.Field) {
+	if typeURL == "" {
+		typeURL = typeURLPrefix + string(m.ProtoReflect().Descriptor().FullName())
+	}
+	if err := checkTypeURL(typeURL, m); err != nil {
+		return "", []zap.Field{zap.Error(err)}
+	}
+
+	b, err := protojson.Marshal(m)
+	if err != nil {
+		return "", []zap.Field{zap.Error(fmt.Errorf("zapcloudlogging: marshal %s: %w", typeURL, err))}
+	}
+	fields, ok := jsonObjectFields(b)
+	if !ok {
+		// Well-known types like google.protobuf.Duration aren't represented as an object.
+		fields = []zap.Field{zap.Reflect("payload", json.RawMessage(b))}
+	}
+	return "", append([]zap.Field{zap.String("@type", typeURL)}, fields...)
+}
+
+// checkTypeURL returns an error if typeURL isn't the type URL of m.
+func checkTypeURL(typeURL string, m proto.Message) error {
+	if !strings.HasPrefix(typeURL, typeURLPrefix) {
+		return fmt.Errorf("zapcloudlogging: type URL %q must start with %q", typeURL, typeURLPrefix)
+	}
+	if want := string(m.ProtoReflect().Descriptor().FullName()); typeURL[len(typeURLPrefix):] != want {
+		return fmt.Errorf("zapcloudlogging: type URL %q doesn't match the message type %s", typeURL, want)
+	}
+	return nil
+}
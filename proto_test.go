@@ -0,0 +1,76 @@
+package zapcloudlogging
+
+import (
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/apipb"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestProtoPayload(t *testing.T) {
+	api := &apipb.Api{Name: "pkg.Service", Version: "v1"}
+	tests := []struct {
+		name    string
+		typeURL string
+		m       proto.Message
+		want    map[string]any
+		wantErr string
+	}{
+		{
+			name: "message",
+			m:    api,
+			want: map[string]any{SeverityKey: "INFO", "@type": "type.googleapis.com/google.protobuf.Api", "name": "pkg.Service", "version": "v1"},
+		},
+		{
+			name:    "type URL",
+			typeURL: "type.googleapis.com/google.protobuf.Api",
+			m:       api,
+			want:    map[string]any{SeverityKey: "INFO", "@type": "type.googleapis.com/google.protobuf.Api", "name": "pkg.Service", "version": "v1"},
+		},
+		{
+			name: "struct",
+			m:    &structpb.Struct{Fields: map[string]*structpb.Value{"count": structpb.NewNumberValue(2)}},
+			want: map[string]any{SeverityKey: "INFO", "@type": "type.googleapis.com/google.protobuf.Struct", "count": 2},
+		},
+		{
+			name: "empty",
+			m:    &emptypb.Empty{},
+			want: map[string]any{SeverityKey: "INFO", "@type": "type.googleapis.com/google.protobuf.Empty"},
+		},
+		{
+			name: "not an object",
+			m:    durationpb.New(1500 * time.Millisecond),
+			want: map[string]any{SeverityKey: "INFO", "@type": "type.googleapis.com/google.protobuf.Duration", "payload": "1.500s"},
+		},
+		{
+			name:    "prefix",
+			typeURL: "google.protobuf.Api",
+			m:       api,
+			wantErr: `zapcloudlogging: type URL "google.protobuf.Api" must start with "type.googleapis.com/"`,
+		},
+		{
+			name:    "mismatched",
+			typeURL: "type.googleapis.com/google.cloud.audit.AuditLog",
+			m:       api,
+			wantErr: `zapcloudlogging: type URL "type.googleapis.com/google.cloud.audit.AuditLog" doesn't match the message type google.protobuf.Api`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, fields := ProtoPayload(tt.typeURL, tt.m)
+			if msg != "" {
+				t.Errorf("message = %q, want empty", msg)
+			}
+			got := encodeFields(t, fields...)
+			if tt.wantErr != "" {
+				assertJSON(t, "entry", got, map[string]any{SeverityKey: "INFO", "error": tt.wantErr})
+				return
+			}
+			assertJSON(t, "entry", got, tt.want)
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package zapcloudlogging
+
+import (
+	"runtime"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type runtimeStats struct {
+	goroutines int
+	mem        *runtime.MemStats
+}
+
+func (s runtimeStats) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddInt("goroutines", s.goroutines)
+	enc.AddUint64("heap_alloc_bytes", s.mem.HeapAlloc)
+	enc.AddUint64("heap_inuse_bytes", s.mem.HeapInuse)
+	enc.AddUint64("heap_objects", s.mem.HeapObjects)
+	enc.AddUint64("sys_bytes", s.mem.Sys)
+	enc.AddUint32("num_gc", s.mem.NumGC)
+	enc.AddString("gc_pause_total", formatDuration(time.Duration(s.mem.PauseTotalNs)))
+	return nil
+}
+
+// RuntimeStats returns the fields for a snapshot of the runtime, e.g. to find leaks by logging it periodically:
+// the number of goroutines, and the heap usage and the GC counts of runtime.MemStats as the runtime object.
+//
+// It calls runtime.ReadMemStats, which stops the world, so call it periodically, e.g. every minute,
+// not for every entry.
+func RuntimeStats() []zap.Field {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	return []zap.Field{
+		zap.Object("runtime", runtimeStats{goroutines: runtime.NumGoroutine(), mem: &mem}),
+	}
+}
@@ -0,0 +1,57 @@
+package zapcloudlogging
+
+import (
+	"runtime"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestRuntimeStats(t *testing.T) {
+	tests := []struct {
+		name  string
+		stats runtimeStats
+		want  map[string]any
+	}{
+		{
+			name:  "zero",
+			stats: runtimeStats{mem: &runtime.MemStats{}},
+			want: map[string]any{
+				"goroutines": 0, "heap_alloc_bytes": 0, "heap_inuse_bytes": 0, "heap_objects": 0,
+				"sys_bytes": 0, "num_gc": 0, "gc_pause_total": "0s",
+			},
+		},
+		{
+			name: "snapshot",
+			stats: runtimeStats{goroutines: 12, mem: &runtime.MemStats{
+				HeapAlloc: 4 << 20, HeapInuse: 6 << 20, HeapObjects: 1500, Sys: 16 << 20,
+				NumGC: 7, PauseTotalNs: 1500000,
+			}},
+			want: map[string]any{
+				"goroutines": 12, "heap_alloc_bytes": 4 << 20, "heap_inuse_bytes": 6 << 20, "heap_objects": 1500,
+				"sys_bytes": 16 << 20, "num_gc": 7, "gc_pause_total": "0.001500s",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := encodeFields(t, zap.Object("runtime", tt.stats))
+			assertJSON(t, "runtime", got["runtime"], tt.want)
+		})
+	}
+
+	t.Run("RuntimeStats", func(t *testing.T) {
+		got, ok := encodeFields(t, RuntimeStats()...)["runtime"].(map[string]any)
+		if !ok {
+			t.Fatalf("runtime = %v, want an object", got)
+		}
+		for _, key := range []string{"goroutines", "heap_alloc_bytes", "heap_inuse_bytes", "heap_objects", "sys_bytes", "num_gc", "gc_pause_total"} {
+			if _, ok := got[key]; !ok {
+				t.Errorf("runtime = %v, want %s", got, key)
+			}
+		}
+		if n, _ := got["goroutines"].(float64); n < 1 {
+			t.Errorf("goroutines = %v, want at least 1", got["goroutines"])
+		}
+	})
+}
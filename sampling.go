@@ -0,0 +1,264 @@
+package zapcloudlogging
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// SampleStat is the number of entries sampled in and out at a level.
+type SampleStat struct {
+	Sampled uint64
+	Dropped uint64
+}
+
+var sampleCounts [zapcore.FatalLevel - zapcore.DebugLevel + 1]SampleStat
+
+// recordSample is the sampling hook of the configs of this package.
+func recordSample(ent zapcore.Entry, dec zapcore.SamplingDecision) {
+	if ent.Level < zapcore.DebugLevel || ent.Level > zapcore.FatalLevel {
+		return
+	}
+	stat := &sampleCounts[ent.Level-zapcore.DebugLevel]
+	if dec&zapcore.LogDropped != 0 {
+		atomic.AddUint64(&stat.Dropped, 1)
+	} else {
+		atomic.AddUint64(&stat.Sampled, 1)
+	}
+}
+
+// SamplingStats returns the number of entries sampled in and out per level
+// by the loggers built from NewProductionConfig and NewDevelopmentConfig.
+func SamplingStats() map[zapcore.Level]SampleStat {
+	stats := make(map[zapcore.Level]SampleStat, len(sampleCounts))
+	for i := range sampleCounts {
+		stats[zapcore.DebugLevel+zapcore.Level(i)] = SampleStat{
+			Sampled: atomic.LoadUint64(&sampleCounts[i].Sampled),
+			Dropped: atomic.LoadUint64(&sampleCounts[i].Dropped),
+		}
+	}
+	return stats
+}
+
+// samplingExemption is the value of a field created by Always.
+type samplingExemption struct{}
+
+// Always returns a zap.Field that exempts the entry from sampling,
+// both the sampling of zap.Config and WithPerTraceSampling.
+//
+// The sampling of zap.Config is exempted only for the loggers built by NewProduction and NewDevelopment
+// with WithSamplingExemptions.
+func Always() zap.Field {
+	return zap.Field{Type: zapcore.SkipType, Interface: samplingExemption{}}
+}
+
+// isAlways reports whether fields contain a field created by Always.
+func isAlways(fields []zapcore.Field) bool {
+	for _, f := range fields {
+		if _, ok := f.Interface.(samplingExemption); ok && f.Type == zapcore.SkipType {
+			return true
+		}
+	}
+	return false
+}
+
+// Security returns a zap.Field that marks the entry as a security event with the security label,
+// e.g. to route it to a dedicated log bucket with a sink filtering on labels.security="true".
+// Security events are never dropped by sampling, see Always.
+func Security() zap.Field {
+	return group(Label("security", "true"), Always())
+}
+
+// WithSamplingExemptions returns an Option that exempts the entries with Always from the sampling of zap.Config.
+//
+// The entries are sampled when they're written instead of when they're checked,
+// so the entries dropped by sampling are still checked by the cores and their fields are still built.
+func WithSamplingExemptions() Option {
+	return optionFunc(func(s *settings) {
+		s.samplingExemptions = true
+	})
+}
+
+// samplerCore samples entries like the sampler of zap.Config,
+// except that it samples them when they're written, so that the entries with Always are exempted.
+type samplerCore struct {
+	zapcore.Core
+	// sampler is a sampler of sampledCore, which only decides whether entries are sampled.
+	sampler zapcore.Core
+	always  bool
+}
+
+// sampledCore is a core that accepts all entries, and discards them.
+// The entries are already enabled by samplerCore at the level they were logged at,
+// which may differ from the level they're written at, e.g. overridden with Severity.
+type sampledCore struct{}
+
+func (sampledCore) Enabled(zapcore.Level) bool {
+	return true
+}
+
+func (c sampledCore) With([]zapcore.Field) zapcore.Core {
+	return c
+}
+
+func (c sampledCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return ce.AddCore(ent, c)
+}
+
+func (sampledCore) Write(zapcore.Entry, []zapcore.Field) error {
+	return nil
+}
+
+func (sampledCore) Sync() error {
+	return nil
+}
+
+func newSamplerCore(core zapcore.Core, cfg *zap.SamplingConfig) zapcore.Core {
+	var opts []zapcore.SamplerOption
+	if cfg.Hook != nil {
+		opts = append(opts, zapcore.SamplerHook(cfg.Hook))
+	}
+	return &samplerCore{
+		Core:    core,
+		sampler: zapcore.NewSamplerWithOptions(sampledCore{}, time.Second, cfg.Initial, cfg.Thereafter, opts...),
+	}
+}
+
+func (c *samplerCore) With(fields []zapcore.Field) zapcore.Core {
+	return &samplerCore{
+		Core:    c.Core.With(fields),
+		sampler: c.sampler.With(fields),
+		always:  c.always || isAlways(fields),
+	}
+}
+
+func (c *samplerCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *samplerCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if !c.always && !isAlways(fields) {
+		sampled := c.sampler.Check(ent, nil)
+		if sampled == nil {
+			return nil
+		}
+		// Writing to sampledCore returns the entry to the pool.
+		sampled.Write()
+	}
+	return c.Core.Write(ent, fields)
+}
+
+// maxSampledTraces is the number of traces whose counts are kept by WithPerTraceSampling.
+const maxSampledTraces = 10000
+
+// WithPerTraceSampling returns an Option that writes at most maxPerTrace entries for each trace,
+// identified by the logging.googleapis.com/trace field, so that a single client can't flood the logs
+// while entries of other traces are still written. Entries without a trace are not limited.
+//
+// The counts are kept for the most recently logged 10000 traces;
+// when a trace is evicted, its count starts over.
+func WithPerTraceSampling(maxPerTrace int) Option {
+	return wrapCore(func(core zapcore.Core) zapcore.Core {
+		return &traceSamplerCore{
+			Core: core,
+			counts: &traceCounts{
+				max:     maxPerTrace,
+				traces:  list.New(),
+				entries: make(map[string]*list.Element),
+			},
+		}
+	})
+}
+
+type traceSamplerCore struct {
+	zapcore.Core
+	trace  string
+	always bool
+	counts *traceCounts
+}
+
+func (c *traceSamplerCore) With(fields []zapcore.Field) zapcore.Core {
+	trace := c.trace
+	if t, ok := traceOf(fields); ok {
+		trace = t
+	}
+	return &traceSamplerCore{
+		Core:   c.Core.With(fields),
+		trace:  trace,
+		always: c.always || isAlways(flatten(fields)),
+		counts: c.counts,
+	}
+}
+
+func (c *traceSamplerCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return checkRewrite(c.Core, ent, ce, c)
+}
+
+func (c *traceSamplerCore) rewrite(ent *zapcore.Entry, fields []zapcore.Field) ([]zapcore.Field, bool) {
+	fields = flatten(fields)
+	trace := c.trace
+	if t, ok := traceOf(fields); ok {
+		trace = t
+	}
+	if trace == "" || c.always || isAlways(fields) {
+		return fields, true
+	}
+	return fields, c.counts.inc(trace)
+}
+
+// traceOf returns the value of the last logging.googleapis.com/trace field in fields.
+func traceOf(fields []zapcore.Field) (string, bool) {
+	for i := len(fields) - 1; i >= 0; i-- {
+		if fields[i].Key == TraceKey && fields[i].Type == zapcore.StringType {
+			return fields[i].String, true
+		}
+	}
+	return "", false
+}
+
+// traceCounts is a LRU cache of the number of entries per trace.
+type traceCounts struct {
+	max int
+
+	mu      sync.Mutex
+	traces  *list.List
+	entries map[string]*list.Element
+}
+
+type traceCount struct {
+	trace string
+	n     int
+}
+
+// inc increments the count of trace, and reports whether it's within the limit.
+func (c *traceCounts) inc(trace string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[trace]
+	if ok {
+		c.traces.MoveToFront(e)
+	} else {
+		if c.traces.Len() >= maxSampledTraces {
+			oldest := c.traces.Back()
+			c.traces.Remove(oldest)
+			delete(c.entries, oldest.Value.(*traceCount).trace)
+		}
+		e = c.traces.PushFront(&traceCount{trace: trace})
+		c.entries[trace] = e
+	}
+
+	tc := e.Value.(*traceCount)
+	if tc.n >= c.max {
+		return false
+	}
+	tc.n++
+	return true
+}
@@ -0,0 +1,184 @@
+package zapcloudlogging
+
+import (
+	"strconv"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestSamplingStats(t *testing.T) {
+	tests := []struct {
+		name  string
+		level zapcore.Level
+		n     int
+		want  SampleStat
+	}{
+		{name: "initial", level: zapcore.InfoLevel, n: 100, want: SampleStat{Sampled: 100}},
+		{name: "thereafter", level: zapcore.WarnLevel, n: 250, want: SampleStat{Sampled: 101, Dropped: 149}},
+		{name: "debug", level: zapcore.DebugLevel, n: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, sink := newTestLogger(t)
+			before := SamplingStats()[tt.level]
+
+			for i := 0; i < tt.n; i++ {
+				logger.Check(tt.level, "msg").Write()
+			}
+
+			after := SamplingStats()[tt.level]
+			got := SampleStat{Sampled: after.Sampled - before.Sampled, Dropped: after.Dropped - before.Dropped}
+			if got != tt.want {
+				t.Errorf("SamplingStats()[%v] increased by %+v, want %+v", tt.level, got, tt.want)
+			}
+			if n := len(sink.entries(t)); uint64(n) != tt.want.Sampled {
+				t.Errorf("got %d entries, want %d", n, tt.want.Sampled)
+			}
+		})
+	}
+}
+
+func TestWithPerTraceSampling(t *testing.T) {
+	trace := func(id string) zap.Field {
+		return zap.String(TraceKey, "projects/p/traces/"+id)
+	}
+	tests := []struct {
+		name string
+		log  func(logger *zap.Logger)
+		want int
+	}{
+		{
+			name: "limited",
+			log: func(logger *zap.Logger) {
+				for i := 0; i < 5; i++ {
+					logger.Info("msg", trace("a"))
+				}
+			},
+			want: 2,
+		},
+		{
+			name: "per trace",
+			log: func(logger *zap.Logger) {
+				for i := 0; i < 3; i++ {
+					logger.Info("msg", trace("a"))
+					logger.Info("msg", trace("b"))
+				}
+			},
+			want: 4,
+		},
+		{
+			name: "with",
+			log: func(logger *zap.Logger) {
+				l := logger.With(trace("a"))
+				for i := 0; i < 3; i++ {
+					l.Info("msg")
+				}
+			},
+			want: 2,
+		},
+		{
+			name: "no trace",
+			log: func(logger *zap.Logger) {
+				for i := 0; i < 5; i++ {
+					logger.Info("msg")
+				}
+			},
+			want: 5,
+		},
+		{
+			name: "always",
+			log: func(logger *zap.Logger) {
+				for i := 0; i < 3; i++ {
+					logger.Info("msg", trace("a"))
+				}
+				logger.Info("msg", trace("a"), Always())
+				logger.With(Security()).Info("msg", trace("a"))
+			},
+			want: 4,
+		},
+		{
+			name: "evicted",
+			log: func(logger *zap.Logger) {
+				logger.Info("msg", trace("a"))
+				logger.Info("msg", trace("a"))
+				for i := 0; i < maxSampledTraces; i++ {
+					logger.Info("msg", trace(strconv.Itoa(i)))
+				}
+				logger.Info("msg", trace("a"))
+			},
+			want: 3 + maxSampledTraces,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, sink := newTestLogger(t, withoutSampling, WithPerTraceSampling(2))
+			tt.log(logger)
+
+			if got := len(sink.entries(t)); got != tt.want {
+				t.Errorf("got %d entries, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAlways(t *testing.T) {
+	tests := []struct {
+		name       string
+		log        func(logger *zap.Logger)
+		opts       []Option
+		want       int
+		wantLabels any
+	}{
+		{
+			name: "sampled",
+			log:  func(logger *zap.Logger) { logger.Info("msg") },
+			want: 100,
+		},
+		{
+			name: "sampled with exemptions",
+			log:  func(logger *zap.Logger) { logger.Info("msg") },
+			opts: []Option{WithSamplingExemptions()},
+			want: 100,
+		},
+		{
+			name: "always without exemptions",
+			log:  func(logger *zap.Logger) { logger.Info("msg", Always()) },
+			want: 100,
+		},
+		{
+			name: "always",
+			log:  func(logger *zap.Logger) { logger.Info("msg", Always()) },
+			opts: []Option{WithSamplingExemptions()},
+			want: 150,
+		},
+		{
+			name: "always with",
+			log:  func(logger *zap.Logger) { logger.With(Always()).Info("msg") },
+			opts: []Option{WithSamplingExemptions()},
+			want: 150,
+		},
+		{
+			name:       "security",
+			log:        func(logger *zap.Logger) { logger.Info("msg", Security()) },
+			opts:       []Option{WithSamplingExemptions()},
+			want:       150,
+			wantLabels: map[string]string{"security": "true"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, sink := newTestLogger(t, tt.opts...)
+			for i := 0; i < 150; i++ {
+				tt.log(logger)
+			}
+
+			entries := sink.entries(t)
+			if len(entries) != tt.want {
+				t.Fatalf("got %d entries, want %d", len(entries), tt.want)
+			}
+			assertJSON(t, "labels", entries[0][LabelsKey], tt.wantLabels)
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package zapcloudlogging
+
+import (
+	"regexp"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// WithValueScrubber returns an Option that replaces the substrings matching any of patterns with replacement
+// in the message and the string fields of every entry, e.g. to keep email addresses or card numbers
+// in free-form messages out of the logs.
+//
+// Only the message and the string fields, e.g. zap.String, are scrubbed, not labels, errors, objects or arrays.
+// Every pattern is matched against every string of every written entry, so the cost grows with the number
+// of patterns and the length of the strings; fields added by With are scrubbed once when they're added.
+func WithValueScrubber(patterns []*regexp.Regexp, replacement string) Option {
+	s := &scrubber{
+		patterns:    append([]*regexp.Regexp(nil), patterns...),
+		replacement: replacement,
+	}
+	return wrapCore(func(core zapcore.Core) zapcore.Core {
+		return &scrubberCore{Core: core, scrubber: s}
+	})
+}
+
+type scrubber struct {
+	patterns    []*regexp.Regexp
+	replacement string
+}
+
+// scrub returns s with the substrings matching the patterns replaced.
+func (s *scrubber) scrub(str string) string {
+	for _, p := range s.patterns {
+		str = p.ReplaceAllString(str, s.replacement)
+	}
+	return str
+}
+
+// scrubFields returns fields with the string fields scrubbed.
+// fields is copied before the first change, so the fields of the caller are never modified.
+func (s *scrubber) scrubFields(fields []zapcore.Field) []zapcore.Field {
+	copied := false
+	for i, f := range fields {
+		if f.Type != zapcore.StringType {
+			continue
+		}
+		scrubbed := s.scrub(f.String)
+		if scrubbed == f.String {
+			continue
+		}
+		if !copied {
+			fields = append([]zapcore.Field(nil), fields...)
+			copied = true
+		}
+		fields[i] = zapcore.Field{Key: f.Key, Type: zapcore.StringType, String: scrubbed}
+	}
+	return fields
+}
+
+type scrubberCore struct {
+	zapcore.Core
+	scrubber *scrubber
+}
+
+func (c *scrubberCore) With(fields []zapcore.Field) zapcore.Core {
+	return &scrubberCore{
+		Core:     c.Core.With(c.scrubber.scrubFields(flatten(fields))),
+		scrubber: c.scrubber,
+	}
+}
+
+func (c *scrubberCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return checkRewrite(c.Core, ent, ce, c)
+}
+
+func (c *scrubberCore) rewrite(ent *zapcore.Entry, fields []zapcore.Field) ([]zapcore.Field, bool) {
+	ent.Message = c.scrubber.scrub(ent.Message)
+	return c.scrubber.scrubFields(flatten(fields)), true
+}
@@ -0,0 +1,81 @@
+package zapcloudlogging
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestWithValueScrubber(t *testing.T) {
+	scrubber := WithValueScrubber([]*regexp.Regexp{
+		regexp.MustCompile(`[\w.+-]+@[\w-]+\.[\w.]+`),
+		regexp.MustCompile(`\b\d{4}(?:[ -]?\d{4}){3}\b`),
+	}, "[REDACTED]")
+
+	tests := []struct {
+		name    string
+		with    []zap.Field
+		msg     string
+		fields  []zap.Field
+		wantMsg string
+		want    map[string]any
+	}{
+		{name: "clean", msg: "signed in", fields: []zap.Field{zap.String("user", "gopher")}, wantMsg: "signed in", want: map[string]any{"user": "gopher"}},
+		{name: "message", msg: "mail to a@example.com failed", wantMsg: "mail to [REDACTED] failed", want: map[string]any{}},
+		{
+			name:    "string field",
+			msg:     "paid",
+			fields:  []zap.Field{zap.String("card", "4111 1111 1111 1111"), zap.Int("amount", 100)},
+			wantMsg: "paid",
+			want:    map[string]any{"card": "[REDACTED]", "amount": 100},
+		},
+		{
+			name:    "with",
+			with:    []zap.Field{zap.String("to", "b@example.org")},
+			msg:     "sent",
+			wantMsg: "sent",
+			want:    map[string]any{"to": "[REDACTED]"},
+		},
+		{
+			name:    "grouped",
+			msg:     "sent",
+			fields:  []zap.Field{group(zap.String("to", "b@example.org"))},
+			wantMsg: "sent",
+			want:    map[string]any{"to": "[REDACTED]"},
+		},
+		{
+			name:    "label not scrubbed",
+			msg:     "sent",
+			fields:  []zap.Field{Label("to", "b@example.org")},
+			wantMsg: "sent",
+			want:    map[string]any{LabelsKey: map[string]string{"to": "b@example.org"}},
+		},
+		{
+			name:    "error not scrubbed",
+			msg:     "failed",
+			fields:  []zap.Field{zap.Error(errors.New("unknown user b@example.org"))},
+			wantMsg: "failed",
+			want:    map[string]any{"error": "unknown user b@example.org"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields := append([]zap.Field(nil), tt.fields...)
+			logger, sink := newTestLogger(t, scrubber)
+			logger.With(tt.with...).Info(tt.msg, fields...)
+
+			got := sink.entries(t)[0]
+			assertJSON(t, MessageKey, got[MessageKey], tt.wantMsg)
+			for k, v := range tt.want {
+				assertJSON(t, k, got[k], v)
+			}
+			for i := range fields {
+				if fields[i].String != tt.fields[i].String {
+					t.Errorf("field %d modified: %q, want %q", i, fields[i].String, tt.fields[i].String)
+				}
+			}
+		})
+	}
+}
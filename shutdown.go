@@ -0,0 +1,89 @@
+package zapcloudlogging
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var (
+	shutdownMu        sync.Mutex
+	shutdownUninstall func()
+)
+
+// InstallShutdownHandler installs a handler of SIGTERM and SIGINT that syncs logger,
+// waiting at most timeout, so that the buffered entries are not lost
+// when Cloud Run or GKE stops the process.
+// After syncing, the handler is uninstalled and the signal is raised again,
+// so the process is terminated unless the program handles the signal by itself.
+//
+// It returns a function that uninstalls the handler, which restores the previous handling of the signals.
+// While a handler is installed, InstallShutdownHandler does nothing and returns the same function.
+func InstallShutdownHandler(logger *zap.Logger, timeout time.Duration) (uninstall func()) {
+	shutdownMu.Lock()
+	defer shutdownMu.Unlock()
+	if shutdownUninstall != nil {
+		return shutdownUninstall
+	}
+
+	sigs := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	var once sync.Once
+	uninstall = func() {
+		once.Do(func() {
+			signal.Stop(sigs)
+			close(done)
+
+			shutdownMu.Lock()
+			shutdownUninstall = nil
+			shutdownMu.Unlock()
+		})
+	}
+	shutdownUninstall = uninstall
+
+	signal.Notify(sigs, syscall.SIGTERM, os.Interrupt)
+	go func() {
+		select {
+		case sig := <-sigs:
+			syncTimeout(logger, timeout)
+			uninstall()
+			raise(sig)
+		case <-done:
+		}
+	}()
+	return uninstall
+}
+
+// syncTimeout syncs logger, waiting at most timeout.
+func syncTimeout(logger *zap.Logger, timeout time.Duration) {
+	synced := make(chan struct{})
+	go func() {
+		defer close(synced)
+		_ = logger.Sync()
+	}()
+
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+	select {
+	case <-synced:
+	case <-t.C:
+	}
+}
+
+// raise raises a signal again after the logger is synced, replaced in tests.
+var raise = raiseSignal
+
+// raiseSignal sends sig to the process itself, or exits if it can't.
+func raiseSignal(sig os.Signal) {
+	p, err := os.FindProcess(os.Getpid())
+	if err == nil {
+		err = p.Signal(sig)
+	}
+	if err != nil {
+		os.Exit(1)
+	}
+}
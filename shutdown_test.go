@@ -0,0 +1,126 @@
+package zapcloudlogging
+
+import (
+	"os"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// slowSyncer is a zapcore.WriteSyncer whose Sync takes delay and is reported to synced.
+type slowSyncer struct {
+	delay  time.Duration
+	synced chan struct{}
+}
+
+func (s slowSyncer) Write(p []byte) (int, error) { return len(p), nil }
+
+func (s slowSyncer) Sync() error {
+	time.Sleep(s.delay)
+	close(s.synced)
+	return nil
+}
+
+// newSyncLogger returns a logger whose Sync takes delay, and a channel closed when it's synced.
+func newSyncLogger(t *testing.T, delay time.Duration) (*zap.Logger, chan struct{}) {
+	core, _ := newTestCore(t)
+	s := slowSyncer{delay: delay, synced: make(chan struct{})}
+	return zap.New(zapcore.NewTee(core, zapcore.NewCore(zapcore.NewJSONEncoder(NewProductionEncoderConfig()), s, zapcore.DebugLevel))), s.synced
+}
+
+func TestSyncTimeout(t *testing.T) {
+	tests := []struct {
+		name       string
+		delay      time.Duration
+		timeout    time.Duration
+		wantSynced bool
+	}{
+		{name: "synced", delay: 0, timeout: time.Second, wantSynced: true},
+		{name: "timed out", delay: time.Second, timeout: 10 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, synced := newSyncLogger(t, tt.delay)
+			start := time.Now()
+			syncTimeout(logger, tt.timeout)
+
+			select {
+			case <-synced:
+				if !tt.wantSynced {
+					t.Error("synced, want timed out")
+				}
+			default:
+				if tt.wantSynced {
+					t.Error("not synced, want synced")
+				}
+			}
+			if elapsed := time.Since(start); elapsed > tt.timeout+500*time.Millisecond {
+				t.Errorf("syncTimeout took %v, want at most %v", elapsed, tt.timeout)
+			}
+		})
+	}
+}
+
+func TestInstallShutdownHandler(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("signals can't be sent to the process itself on Windows")
+	}
+	tests := []struct {
+		name string
+		sig  syscall.Signal
+	}{
+		{name: "SIGTERM", sig: syscall.SIGTERM},
+		{name: "SIGINT", sig: syscall.SIGINT},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raised := make(chan os.Signal, 1)
+			raise = func(sig os.Signal) { raised <- sig }
+			t.Cleanup(func() { raise = raiseSignal })
+
+			logger, synced := newSyncLogger(t, 0)
+			uninstall := InstallShutdownHandler(logger, time.Second)
+			defer uninstall()
+			// The handler is installed only once, so the logger of this call is never synced.
+			other, otherSynced := newSyncLogger(t, 0)
+			InstallShutdownHandler(other, time.Second)
+
+			p, err := os.FindProcess(os.Getpid())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := p.Signal(tt.sig); err != nil {
+				t.Fatal(err)
+			}
+			select {
+			case sig := <-raised:
+				if sig != tt.sig {
+					t.Errorf("raised %v, want %v", sig, tt.sig)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("the signal isn't raised again")
+			}
+			select {
+			case <-synced:
+			default:
+				t.Error("the logger isn't synced before the signal is raised")
+			}
+			select {
+			case <-otherSynced:
+				t.Error("the logger of the second call is synced, want only the first")
+			default:
+			}
+
+			shutdownMu.Lock()
+			installed := shutdownUninstall != nil
+			shutdownMu.Unlock()
+			if installed {
+				t.Error("the handler is still installed after the signal")
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package zapcloudlogging
+
+import (
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// redactedArg replaces the values of the arguments redacted by WithRedactedArgs.
+const redactedArg = "[REDACTED]"
+
+type sqlQuery struct {
+	statement string
+	args      []any
+	redact    bool
+	duration  time.Duration
+}
+
+func (q sqlQuery) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("statement", q.statement)
+	enc.AddInt("args_count", len(q.args))
+	if len(q.args) > 0 {
+		err := enc.AddArray("args", zapcore.ArrayMarshalerFunc(func(enc zapcore.ArrayEncoder) error {
+			for _, arg := range q.args {
+				if q.redact {
+					enc.AppendString(redactedArg)
+				} else if err := enc.AppendReflected(arg); err != nil {
+					return err
+				}
+			}
+			return nil
+		}))
+		if err != nil {
+			return err
+		}
+	}
+	enc.AddString("duration", formatDuration(q.duration))
+	return nil
+}
+
+type sqlOptions struct {
+	redactArgs bool
+}
+
+// A SQLOption configures the fields returned by SQL.
+type SQLOption func(*sqlOptions)
+
+// WithRedactedArgs replaces the values of the arguments with "[REDACTED]",
+// for queries whose arguments may contain personal data or secrets.
+// The number of the arguments is still logged.
+func WithRedactedArgs() SQLOption {
+	return func(o *sqlOptions) {
+		o.redactArgs = true
+	}
+}
+
+// SQL returns the fields for a SQL query executed with args in d,
+// e.g. to log slow queries: the operation (e.g. SELECT) and the table of the query are set
+// as the db.operation and db.table labels, and the query, the arguments and the duration
+// as a google.protobuf.Duration are logged as the sql object.
+// The query isn't set as a label, so that the labels keep a low cardinality.
+func SQL(query string, args []any, d time.Duration, opts ...SQLOption) []zap.Field {
+	var o sqlOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	var fields []zap.Field
+	op, table := sqlOperation(query)
+	if op != "" {
+		fields = append(fields, Label("db.operation", op))
+	}
+	if table != "" {
+		fields = append(fields, Label("db.table", table))
+	}
+	return append(fields, zap.Object("sql", sqlQuery{
+		statement: query,
+		args:      args,
+		redact:    o.redactArgs,
+		duration:  d,
+	}))
+}
+
+// sqlQuotes removes the quotes of the identifiers.
+var sqlQuotes = strings.NewReplacer("`", "", `"`, "", "[", "", "]", "")
+
+// sqlOperation returns the first keyword of query in upper case,
+// and the first table name following FROM, INTO, UPDATE or TABLE.
+// It doesn't parse the query, so table is empty for a subquery or if no name is found.
+func sqlOperation(query string) (op, table string) {
+	words := strings.Fields(query)
+	if len(words) == 0 {
+		return "", ""
+	}
+	op = strings.ToUpper(strings.TrimRight(words[0], "(;"))
+	for i := 0; i+1 < len(words); i++ {
+		switch strings.ToUpper(words[i]) {
+		case "FROM", "INTO", "UPDATE", "TABLE":
+			name := words[i+1]
+			if j := strings.IndexAny(name, "(),;"); j >= 0 {
+				name = name[:j]
+			}
+			if name = sqlQuotes.Replace(name); name != "" {
+				return op, name
+			}
+		}
+	}
+	return op, ""
+}
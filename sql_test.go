@@ -0,0 +1,85 @@
+package zapcloudlogging
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSQL(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		args   []any
+		opts   []SQLOption
+		labels map[string]string
+		sql    map[string]any
+	}{
+		{
+			name:   "select",
+			query:  "SELECT id, name FROM users WHERE id = ?",
+			args:   []any{42},
+			labels: map[string]string{"db.operation": "SELECT", "db.table": "users"},
+			sql: map[string]any{
+				"statement":  "SELECT id, name FROM users WHERE id = ?",
+				"args_count": 1,
+				"args":       []any{42},
+				"duration":   "0.250s",
+			},
+		},
+		{
+			name:   "insert",
+			query:  "insert into `users`(id, name) values (?, ?)",
+			args:   []any{42, "gopher"},
+			opts:   []SQLOption{WithRedactedArgs()},
+			labels: map[string]string{"db.operation": "INSERT", "db.table": "users"},
+			sql: map[string]any{
+				"statement":  "insert into `users`(id, name) values (?, ?)",
+				"args_count": 2,
+				"args":       []any{redactedArg, redactedArg},
+				"duration":   "0.250s",
+			},
+		},
+		{
+			name:   "update with schema",
+			query:  `UPDATE "public"."users" SET name = $1`,
+			labels: map[string]string{"db.operation": "UPDATE", "db.table": "public.users"},
+			sql: map[string]any{
+				"statement":  `UPDATE "public"."users" SET name = $1`,
+				"args_count": 0,
+				"duration":   "0.250s",
+			},
+		},
+		{
+			name:   "subquery",
+			query:  "SELECT count(*) FROM (SELECT 1)",
+			labels: map[string]string{"db.operation": "SELECT"},
+			sql: map[string]any{
+				"statement":  "SELECT count(*) FROM (SELECT 1)",
+				"args_count": 0,
+				"duration":   "0.250s",
+			},
+		},
+		{
+			name:  "empty",
+			query: "",
+			sql: map[string]any{
+				"statement":  "",
+				"args_count": 0,
+				"duration":   "0.250s",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := encodeFields(t, SQL(tt.query, tt.args, 250*time.Millisecond, tt.opts...)...)
+			if tt.labels == nil {
+				if labels, ok := got[LabelsKey]; ok {
+					t.Errorf("labels = %v, want none", labels)
+				}
+			} else {
+				assertJSON(t, "labels", got[LabelsKey], tt.labels)
+			}
+			assertJSON(t, "sql", got["sql"], tt.sql)
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package zapcloudlogging
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/multierr"
+	"go.uber.org/zap/zapcore"
+)
+
+// MultiSyncer is a zapcore.WriteSyncer that duplicates writes to the WriteSyncers,
+// which can be added and removed while it's written,
+// e.g. to start mirroring entries to a file during an incident without restarting the process.
+type MultiSyncer struct {
+	mu sync.Mutex // serializes Add and Remove
+	// syncers is the current []*syncerEntry, replaced instead of modified by Add and Remove.
+	syncers atomic.Value
+}
+
+// syncerEntry is a WriteSyncer of MultiSyncer, identified by its pointer,
+// so that the WriteSyncers that aren't comparable can be removed.
+type syncerEntry struct {
+	zapcore.WriteSyncer
+}
+
+// NewMultiSyncer returns a MultiSyncer that writes to ws.
+func NewMultiSyncer(ws ...zapcore.WriteSyncer) *MultiSyncer {
+	syncers := make([]*syncerEntry, len(ws))
+	for i, w := range ws {
+		syncers[i] = &syncerEntry{w}
+	}
+	s := &MultiSyncer{}
+	s.syncers.Store(syncers)
+	return s
+}
+
+func (s *MultiSyncer) load() []*syncerEntry {
+	return s.syncers.Load().([]*syncerEntry)
+}
+
+// Add adds ws to the WriteSyncers written to,
+// and returns a function that removes it and reports whether it was removed.
+// The function removes the added ws even if it isn't comparable, unlike Remove.
+func (s *MultiSyncer) Add(ws zapcore.WriteSyncer) (remove func() bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e := &syncerEntry{ws}
+	old := s.load()
+	syncers := make([]*syncerEntry, len(old), len(old)+1)
+	copy(syncers, old)
+	s.syncers.Store(append(syncers, e))
+	return func() bool {
+		return s.remove(func(o *syncerEntry) bool { return o == e })
+	}
+}
+
+// Remove removes ws from the WriteSyncers written to, and reports whether ws was removed.
+// ws is compared with ==, so it must be the value passed to NewMultiSyncer or Add.
+// The WriteSyncers of types that aren't comparable, e.g. structs with slices, are never removed by Remove;
+// use the function returned by Add instead.
+// Ongoing writes may still write to ws.
+func (s *MultiSyncer) Remove(ws zapcore.WriteSyncer) bool {
+	t := reflect.TypeOf(ws)
+	if t == nil || !t.Comparable() {
+		return false
+	}
+	return s.remove(func(e *syncerEntry) bool {
+		return reflect.TypeOf(e.WriteSyncer) == t && e.WriteSyncer == ws
+	})
+}
+
+// remove removes the first WriteSyncer matching match, and reports whether it was removed.
+func (s *MultiSyncer) remove(match func(*syncerEntry) bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old := s.load()
+	for i, e := range old {
+		if match(e) {
+			syncers := make([]*syncerEntry, 0, len(old)-1)
+			syncers = append(syncers, old[:i]...)
+			s.syncers.Store(append(syncers, old[i+1:]...))
+			return true
+		}
+	}
+	return false
+}
+
+// Write writes p to all the WriteSyncers.
+// Like zapcore.NewMultiWriteSyncer, an error of a WriteSyncer doesn't stop writing to the others.
+func (s *MultiSyncer) Write(p []byte) (int, error) {
+	var err error
+	n := len(p)
+	for _, ws := range s.load() {
+		m, werr := ws.Write(p)
+		err = multierr.Append(err, werr)
+		if werr == nil && m < n {
+			n = m
+		}
+	}
+	return n, err
+}
+
+// Sync syncs all the WriteSyncers.
+func (s *MultiSyncer) Sync() error {
+	var err error
+	for _, ws := range s.load() {
+		err = multierr.Append(err, ws.Sync())
+	}
+	return err
+}
@@ -0,0 +1,139 @@
+package zapcloudlogging
+
+import (
+	"errors"
+	"testing"
+)
+
+// failingSyncer is a zapcore.WriteSyncer failing with err.
+type failingSyncer struct {
+	err error
+}
+
+func (s failingSyncer) Write(p []byte) (int, error) { return 0, s.err }
+func (s failingSyncer) Sync() error                 { return s.err }
+
+func TestMultiSyncer(t *testing.T) {
+	a, b, c := &testSink{}, &testSink{}, &testSink{}
+	s := NewMultiSyncer(a)
+
+	tests := []struct {
+		name  string
+		apply func()
+		want  [3]string
+	}{
+		{name: "initial", want: [3]string{"1"}},
+		{name: "added", apply: func() { s.Add(b) }, want: [3]string{"12", "2"}},
+		{name: "added again", apply: func() { s.Add(c) }, want: [3]string{"123", "23", "3"}},
+		{
+			name: "removed",
+			apply: func() {
+				if !s.Remove(b) {
+					t.Error("Remove(b) = false, want true")
+				}
+			},
+			want: [3]string{"1234", "23", "34"},
+		},
+		{
+			name: "removed twice",
+			apply: func() {
+				if s.Remove(b) {
+					t.Error("Remove(b) = true, want false")
+				}
+			},
+			want: [3]string{"12345", "23", "345"},
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.apply != nil {
+				tt.apply()
+			}
+			p := []byte{byte('1' + i)}
+			if n, err := s.Write(p); n != 1 || err != nil {
+				t.Errorf("Write() = %d, %v, want 1, nil", n, err)
+			}
+			for j, sink := range []*testSink{a, b, c} {
+				if got := sink.String(); got != tt.want[j] {
+					t.Errorf("sink %d = %q, want %q", j, got, tt.want[j])
+				}
+			}
+		})
+	}
+}
+
+func TestMultiSyncerErrors(t *testing.T) {
+	errWrite := errors.New("write failed")
+	sink := &testSink{}
+	s := NewMultiSyncer(failingSyncer{errWrite}, sink)
+
+	n, err := s.Write([]byte("msg"))
+	if n != 3 || !errors.Is(err, errWrite) {
+		t.Errorf("Write() = %d, %v, want 3, %v", n, err, errWrite)
+	}
+	if got := sink.String(); got != "msg" {
+		t.Errorf("sink = %q, want %q", got, "msg")
+	}
+	if err := s.Sync(); !errors.Is(err, errWrite) {
+		t.Errorf("Sync() = %v, want %v", err, errWrite)
+	}
+}
+
+// sliceSyncer is a zapcore.WriteSyncer that isn't comparable.
+type sliceSyncer struct {
+	sink *testSink
+	_    []string
+}
+
+func (s sliceSyncer) Write(p []byte) (int, error) { return s.sink.Write(p) }
+func (s sliceSyncer) Sync() error                 { return nil }
+
+func TestMultiSyncerNotComparable(t *testing.T) {
+	tests := []struct {
+		name   string
+		remove func(s *MultiSyncer, ws sliceSyncer, removeAdded func() bool) bool
+		want   bool
+		// wantSink is written by the WriteSyncer of NewMultiSyncer, and by the added one unless it's removed.
+		wantSink string
+	}{
+		{
+			name:     "Remove",
+			remove:   func(s *MultiSyncer, ws sliceSyncer, _ func() bool) bool { return s.Remove(ws) },
+			want:     false,
+			wantSink: "11",
+		},
+		{
+			name:     "added",
+			remove:   func(_ *MultiSyncer, _ sliceSyncer, removeAdded func() bool) bool { return removeAdded() },
+			want:     true,
+			wantSink: "1",
+		},
+		{
+			name: "added twice",
+			remove: func(_ *MultiSyncer, _ sliceSyncer, removeAdded func() bool) bool {
+				removeAdded()
+				return removeAdded()
+			},
+			want:     false,
+			wantSink: "1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := &testSink{}, &testSink{}
+			ws := sliceSyncer{sink: b}
+			s := NewMultiSyncer(a, sliceSyncer{sink: b})
+			removeAdded := s.Add(ws)
+
+			if got := tt.remove(s, ws, removeAdded); got != tt.want {
+				t.Errorf("removed = %v, want %v", got, tt.want)
+			}
+			if _, err := s.Write([]byte("1")); err != nil {
+				t.Fatal(err)
+			}
+			if got := b.String(); got != tt.wantSink {
+				t.Errorf("sink = %q, want %q", got, tt.wantSink)
+			}
+		})
+	}
+}
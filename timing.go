@@ -0,0 +1,47 @@
+package zapcloudlogging
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// now returns the current time, replaced in tests.
+var now = time.Now
+
+// LogSpan returns a function that logs the name and the time elapsed since LogSpan was called,
+// typically deferred to time a function:
+//
+//	defer zapcloudlogging.LogSpan(logger, "load")()
+//
+// The entry is logged at INFO with name as the message and the span label,
+// and the elapsed time as a google.protobuf.Duration.
+func LogSpan(logger *zap.Logger, name string) func() {
+	// The caller is the function calling the returned function.
+	logger = logger.WithOptions(zap.AddCallerSkip(1))
+	start := now()
+	return func() {
+		logger.Info(name,
+			Label("span", name),
+			DurationProto("elapsed", now().Sub(start)),
+		)
+	}
+}
+
+// LogIfSlow returns a function that logs msg at WARNING with the time elapsed since LogIfSlow was called
+// as a google.protobuf.Duration, only if it exceeds threshold, typically deferred to watch a function:
+//
+//	defer zapcloudlogging.LogIfSlow(logger, time.Second, "slow query")()
+func LogIfSlow(logger *zap.Logger, threshold time.Duration, msg string) func() {
+	// The caller is the function calling the returned function.
+	logger = logger.WithOptions(zap.AddCallerSkip(1))
+	start := now()
+	return func() {
+		if elapsed := now().Sub(start); elapsed > threshold {
+			logger.Warn(msg,
+				DurationProto("elapsed", elapsed),
+				DurationProto("threshold", threshold),
+			)
+		}
+	}
+}
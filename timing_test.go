@@ -0,0 +1,92 @@
+package zapcloudlogging
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeClock is a clock advanced by the tests, replacing now.
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	return c.t
+}
+
+// setFakeClock replaces now with a fake clock for the test.
+func setFakeClock(t *testing.T) *fakeClock {
+	c := &fakeClock{t: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}
+	now = c.now
+	t.Cleanup(func() { now = time.Now })
+	return c
+}
+
+func TestLogSpan(t *testing.T) {
+	tests := []struct {
+		name    string
+		elapsed time.Duration
+		want    string
+	}{
+		{name: "zero", elapsed: 0, want: "0s"},
+		{name: "milliseconds", elapsed: 1500 * time.Millisecond, want: "1.500s"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := setFakeClock(t)
+			logger, sink := newTestLogger(t)
+
+			done := LogSpan(logger, "load")
+			clock.t = clock.t.Add(tt.elapsed)
+			done()
+
+			entries := sink.entries(t)
+			if len(entries) != 1 {
+				t.Fatalf("got %d entries, want 1", len(entries))
+			}
+			e := entries[0]
+			if e[SeverityKey] != "INFO" || e[MessageKey] != "load" || e["elapsed"] != tt.want {
+				t.Errorf("entry = %v, want INFO load with elapsed %s", e, tt.want)
+			}
+			assertJSON(t, "labels", e[LabelsKey], map[string]string{"span": "load"})
+		})
+	}
+}
+
+func TestLogIfSlow(t *testing.T) {
+	tests := []struct {
+		name    string
+		elapsed time.Duration
+		// want is the elapsed time logged, or empty if nothing is logged.
+		want string
+	}{
+		{name: "fast", elapsed: 100 * time.Millisecond},
+		{name: "at threshold", elapsed: time.Second},
+		{name: "slow", elapsed: 1500 * time.Millisecond, want: "1.500s"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := setFakeClock(t)
+			logger, sink := newTestLogger(t)
+
+			done := LogIfSlow(logger, time.Second, "slow query")
+			clock.t = clock.t.Add(tt.elapsed)
+			done()
+
+			entries := sink.entries(t)
+			if tt.want == "" {
+				if len(entries) != 0 {
+					t.Errorf("got %v, want no entries", entries)
+				}
+				return
+			}
+			if len(entries) != 1 {
+				t.Fatalf("got %d entries, want 1", len(entries))
+			}
+			e := entries[0]
+			if e[SeverityKey] != "WARNING" || e[MessageKey] != "slow query" || e["elapsed"] != tt.want || e["threshold"] != "1s" {
+				t.Errorf("entry = %v, want WARNING slow query with elapsed %s", e, tt.want)
+			}
+		})
+	}
+}
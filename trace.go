@@ -0,0 +1,270 @@
+package zapcloudlogging
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// TraceResource returns a zap.Field that sets logging.googleapis.com/trace to fullName as is,
+// which must be a resource name like "projects/my-project/traces/06796866738c859f2f19b7cfb3214824".
+// If fullName doesn't start with "projects/", e.g. it's a bare trace ID, the field is skipped
+// since Cloud Logging can't associate it with the trace.
+func TraceResource(fullName string) zap.Field {
+	if !strings.HasPrefix(fullName, "projects/") {
+		return zap.Skip()
+	}
+	return zap.String(TraceKey, fullName)
+}
+
+// SpanName returns a zap.Field that sets the name of the span the entry is logged in, e.g. "db.query",
+// as the span_name label, so that entries can be filtered by the span name.
+// It doesn't associate the entry with the span; the logging.googleapis.com/spanId field does.
+func SpanName(name string) zap.Field {
+	return Label("span_name", name)
+}
+
+// CloudTraceContextHeader is the HTTP header of the trace context of Google Cloud.
+//
+// https://cloud.google.com/trace/docs/trace-context#legacy-http-header
+const CloudTraceContextHeader = "X-Cloud-Trace-Context"
+
+// traceContext is a trace and a span in the trace.
+type traceContext struct {
+	traceID string
+	// spanID is a 16-character hexadecimal string.
+	spanID  string
+	sampled bool
+}
+
+// parseCloudTraceContext parses a value of the X-Cloud-Trace-Context header,
+// formatted as "TRACE_ID/SPAN_ID;o=OPTIONS", where SPAN_ID is a decimal number.
+func parseCloudTraceContext(v string) (traceContext, bool) {
+	var tc traceContext
+	v, options, _ := strings.Cut(v, ";")
+	tc.traceID, v, _ = strings.Cut(v, "/")
+	if !isHex(tc.traceID, 32) {
+		return traceContext{}, false
+	}
+	if v != "" {
+		span, err := strconv.ParseUint(v, 10, 64)
+		if err == nil && span != 0 {
+			tc.spanID = fmt.Sprintf("%016x", span)
+		}
+	}
+	tc.sampled = options == "o=1"
+	return tc, true
+}
+
+// header returns tc as a value of the X-Cloud-Trace-Context header.
+func (tc traceContext) header() string {
+	v := tc.traceID
+	if tc.spanID != "" {
+		if span, err := strconv.ParseUint(tc.spanID, 16, 64); err == nil {
+			v += "/" + strconv.FormatUint(span, 10)
+		}
+	}
+	if tc.sampled {
+		v += ";o=1"
+	} else {
+		v += ";o=0"
+	}
+	return v
+}
+
+// fields returns the fields that associate an entry with tc.
+// The trace field is omitted if projectID is empty, see TraceResource.
+func (tc traceContext) fields(projectID string) []zap.Field {
+	fields := make([]zap.Field, 0, 3)
+	if projectID != "" {
+		fields = append(fields, zap.String(TraceKey, "projects/"+projectID+"/traces/"+tc.traceID))
+	}
+	if tc.spanID != "" {
+		fields = append(fields, zap.String(SpanIDKey, tc.spanID))
+	}
+	fields = append(fields, zap.Bool(TraceSampledKey, tc.sampled))
+	return fields
+}
+
+// isHex reports whether s is a non-zero hexadecimal string of n characters.
+func isHex(s string, n int) bool {
+	if len(s) != n || strings.Trim(s, "0") == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
+
+// TraceContextFromHeader returns the trace, span and sampled fields for the X-Cloud-Trace-Context header in h,
+// so that the entry is associated with the trace of the request.
+// If the header is absent or malformed, it returns nil.
+// The trace field needs the project ID, so it's omitted if projectID is empty.
+//
+// https://cloud.google.com/trace/docs/trace-log-integration
+func TraceContextFromHeader(h http.Header, projectID string) []zap.Field {
+	tc, ok := parseCloudTraceContext(h.Get(CloudTraceContextHeader))
+	if !ok {
+		return nil
+	}
+	return tc.fields(projectID)
+}
+
+// TraceFieldsFromDecision returns the trace, span and sampled fields for a span of a trace
+// and the sampling decision made by the service, e.g. by a sampler of OpenTelemetry,
+// so that the entries of the sampled traces are shown with the traces.
+// traceID is a 32-character and spanID is a 16-character hexadecimal string; spanID can be empty.
+// If traceID is malformed, it returns nil, and if spanID is malformed, the span field is omitted.
+// The trace field needs the project ID, so it's omitted if projectID is empty.
+func TraceFieldsFromDecision(projectID, traceID, spanID string, sampled bool) []zap.Field {
+	if !isHex(traceID, 32) {
+		return nil
+	}
+	if !isHex(spanID, 16) {
+		spanID = ""
+	}
+	return traceContext{traceID: traceID, spanID: spanID, sampled: sampled}.fields(projectID)
+}
+
+// grpcTraceBinKey is the gRPC metadata key of the binary trace context of OpenCensus.
+const grpcTraceBinKey = "grpc-trace-bin"
+
+// parseGRPCTraceBin parses the binary trace context of OpenCensus:
+// a version byte 0, then the trace ID (field 0, 16 bytes), the span ID (field 1, 8 bytes)
+// and the trace options (field 2, 1 byte), each preceded by the field ID.
+//
+// https://github.com/census-instrumentation/opencensus-specs/blob/master/encodings/BinaryEncoding.md
+func parseGRPCTraceBin(b []byte) (traceContext, bool) {
+	if len(b) == 0 || b[0] != 0 {
+		return traceContext{}, false
+	}
+	b = b[1:]
+
+	var tc traceContext
+	for len(b) > 0 {
+		var n int
+		switch b[0] {
+		case 0:
+			n = 16
+		case 1:
+			n = 8
+		case 2:
+			n = 1
+		default:
+			// Unknown fields can't be skipped since their lengths are unknown.
+			return tc, tc.traceID != ""
+		}
+		if len(b) < 1+n {
+			return traceContext{}, false
+		}
+		v := b[1 : 1+n]
+		switch b[0] {
+		case 0:
+			tc.traceID = hex.EncodeToString(v)
+		case 1:
+			tc.spanID = hex.EncodeToString(v)
+		case 2:
+			tc.sampled = v[0]&1 != 0
+		}
+		b = b[1+n:]
+	}
+
+	if !isHex(tc.traceID, 32) {
+		return traceContext{}, false
+	}
+	if tc.spanID != "" && !isHex(tc.spanID, 16) {
+		tc.spanID = ""
+	}
+	return tc, true
+}
+
+// TraceContextFromGRPCBin returns the trace, span and sampled fields for the grpc-trace-bin metadata,
+// the binary trace context of OpenCensus propagated by gRPC.
+// md is typically a metadata.MD of google.golang.org/grpc/metadata.
+// If the metadata is absent or malformed, it returns nil.
+// The trace field needs the project ID, so it's omitted if projectID is empty.
+func TraceContextFromGRPCBin(md map[string][]string, projectID string) []zap.Field {
+	vs := md[grpcTraceBinKey]
+	if len(vs) == 0 {
+		return nil
+	}
+	tc, ok := parseGRPCTraceBin([]byte(vs[0]))
+	if !ok {
+		return nil
+	}
+	return tc.fields(projectID)
+}
+
+// The HTTP headers of the B3 propagation of Zipkin.
+//
+// https://github.com/openzipkin/b3-propagation
+const (
+	b3Header        = "b3"
+	b3TraceIDHeader = "X-B3-TraceId"
+	b3SpanIDHeader  = "X-B3-SpanId"
+	b3SampledHeader = "X-B3-Sampled"
+	b3FlagsHeader   = "X-B3-Flags"
+)
+
+// parseB3 parses a B3 trace ID, span ID and sampling state.
+// A 64-bit trace ID is left-padded with zeros to 128 bits.
+func parseB3(traceID, spanID, sampled string) (traceContext, bool) {
+	if len(traceID) == 16 {
+		traceID = "0000000000000000" + traceID
+	}
+	if !isHex(traceID, 32) {
+		return traceContext{}, false
+	}
+	tc := traceContext{traceID: strings.ToLower(traceID)}
+	if isHex(spanID, 16) {
+		tc.spanID = strings.ToLower(spanID)
+	}
+	// "d" is the debug flag, which implies the trace is sampled.
+	tc.sampled = sampled == "1" || sampled == "d" || strings.EqualFold(sampled, "true")
+	return tc, true
+}
+
+// parseB3Header parses a value of the single b3 header,
+// formatted as "TRACE_ID-SPAN_ID-SAMPLING_STATE-PARENT_SPAN_ID", where the last two are optional.
+// A value with only the sampling state, e.g. "0", has no trace and isn't parsed.
+func parseB3Header(v string) (traceContext, bool) {
+	parts := strings.Split(v, "-")
+	if len(parts) < 2 {
+		return traceContext{}, false
+	}
+	var sampled string
+	if len(parts) > 2 {
+		sampled = parts[2]
+	}
+	return parseB3(parts[0], parts[1], sampled)
+}
+
+// TraceContextFromB3 returns the trace, span and sampled fields for the B3 headers of Zipkin in h,
+// either the single b3 header or the X-B3-TraceId, X-B3-SpanId and X-B3-Sampled headers;
+// the single header is used if both are present. A 64-bit trace ID is left-padded with zeros.
+// If the headers are absent or malformed, it returns nil.
+// The trace field needs the project ID, so it's omitted if projectID is empty.
+//
+// https://github.com/openzipkin/b3-propagation
+func TraceContextFromB3(h http.Header, projectID string) []zap.Field {
+	tc, ok := parseB3Header(h.Get(b3Header))
+	if !ok {
+		sampled := h.Get(b3SampledHeader)
+		if h.Get(b3FlagsHeader) == "1" {
+			sampled = "d"
+		}
+		tc, ok = parseB3(h.Get(b3TraceIDHeader), h.Get(b3SpanIDHeader), sampled)
+	}
+	if !ok {
+		return nil
+	}
+	return tc.fields(projectID)
+}
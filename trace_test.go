@@ -0,0 +1,399 @@
+package zapcloudlogging
+
+import (
+	"encoding/hex"
+	"net/http"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+const (
+	testTraceID = "06796866738c859f2f19b7cfb3214824"
+	testSpanID  = "000000000000004a"
+)
+
+// traceFieldsOf returns the trace, span and sampled fields of the entry logged with fields.
+func traceFieldsOf(t *testing.T, fields []zap.Field) map[string]any {
+	t.Helper()
+	got := encodeFields(t, fields...)
+	trace := make(map[string]any)
+	for _, key := range []string{TraceKey, SpanIDKey, TraceSampledKey} {
+		if v, ok := got[key]; ok {
+			trace[key] = v
+		}
+	}
+	return trace
+}
+
+func TestTraceFieldsFromDecision(t *testing.T) {
+	tests := []struct {
+		name      string
+		projectID string
+		traceID   string
+		spanID    string
+		sampled   bool
+		want      map[string]any
+	}{
+		{
+			name:      "sampled",
+			projectID: "my-project",
+			traceID:   testTraceID,
+			spanID:    testSpanID,
+			sampled:   true,
+			want: map[string]any{
+				TraceKey:        "projects/my-project/traces/" + testTraceID,
+				SpanIDKey:       testSpanID,
+				TraceSampledKey: true,
+			},
+		},
+		{
+			name:    "no project",
+			traceID: testTraceID,
+			spanID:  testSpanID,
+			want:    map[string]any{SpanIDKey: testSpanID, TraceSampledKey: false},
+		},
+		{
+			name:      "no span",
+			projectID: "my-project",
+			traceID:   testTraceID,
+			want: map[string]any{
+				TraceKey:        "projects/my-project/traces/" + testTraceID,
+				TraceSampledKey: false,
+			},
+		},
+		{
+			name:      "malformed span",
+			projectID: "my-project",
+			traceID:   testTraceID,
+			spanID:    "not-a-span-id!!!",
+			sampled:   true,
+			want: map[string]any{
+				TraceKey:        "projects/my-project/traces/" + testTraceID,
+				TraceSampledKey: true,
+			},
+		},
+		{
+			name:      "zero span",
+			projectID: "my-project",
+			traceID:   testTraceID,
+			spanID:    "0000000000000000",
+			want: map[string]any{
+				TraceKey:        "projects/my-project/traces/" + testTraceID,
+				TraceSampledKey: false,
+			},
+		},
+		{
+			name:      "empty trace",
+			projectID: "my-project",
+			spanID:    testSpanID,
+			want:      map[string]any{},
+		},
+		{
+			name:      "short trace",
+			projectID: "my-project",
+			traceID:   testTraceID[:16],
+			spanID:    testSpanID,
+			want:      map[string]any{},
+		},
+		{
+			name:      "zero trace",
+			projectID: "my-project",
+			traceID:   "00000000000000000000000000000000",
+			spanID:    testSpanID,
+			want:      map[string]any{},
+		},
+		{
+			name:      "non-hex trace",
+			projectID: "my-project",
+			traceID:   "06796866738c859f2f19b7cfb321482z",
+			spanID:    testSpanID,
+			want:      map[string]any{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields := TraceFieldsFromDecision(tt.projectID, tt.traceID, tt.spanID, tt.sampled)
+			if len(tt.want) == 0 && fields != nil {
+				t.Errorf("got %d fields, want nil", len(fields))
+			}
+			assertJSON(t, "trace", traceFieldsOf(t, fields), tt.want)
+		})
+	}
+}
+
+func TestSpanName(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields []zap.Field
+		want   map[string]string
+	}{
+		{name: "span name", fields: []zap.Field{SpanName("db.query")}, want: map[string]string{"span_name": "db.query"}},
+		{
+			name:   "with labels",
+			fields: []zap.Field{Label("env", "prod"), SpanName("db.query")},
+			want:   map[string]string{"env": "prod", "span_name": "db.query"},
+		},
+		{
+			name:   "innermost wins",
+			fields: []zap.Field{SpanName("http.request"), SpanName("db.query")},
+			want:   map[string]string{"span_name": "db.query"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := encodeFields(t, tt.fields...)
+			assertJSON(t, "labels", got[LabelsKey], tt.want)
+			if _, ok := got[SpanIDKey]; ok {
+				t.Errorf("%s = %v, want none", SpanIDKey, got[SpanIDKey])
+			}
+		})
+	}
+}
+
+func TestTraceContextFromHeader(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   map[string]any
+	}{
+		{
+			name:   "sampled",
+			header: testTraceID + "/74;o=1",
+			want: map[string]any{
+				TraceKey:        "projects/my-project/traces/" + testTraceID,
+				SpanIDKey:       testSpanID,
+				TraceSampledKey: true,
+			},
+		},
+		{
+			name:   "not sampled",
+			header: testTraceID + "/74;o=0",
+			want: map[string]any{
+				TraceKey:        "projects/my-project/traces/" + testTraceID,
+				SpanIDKey:       testSpanID,
+				TraceSampledKey: false,
+			},
+		},
+		{
+			name:   "trace only",
+			header: testTraceID,
+			want: map[string]any{
+				TraceKey:        "projects/my-project/traces/" + testTraceID,
+				TraceSampledKey: false,
+			},
+		},
+		{
+			name:   "malformed span",
+			header: testTraceID + "/abc;o=1",
+			want: map[string]any{
+				TraceKey:        "projects/my-project/traces/" + testTraceID,
+				TraceSampledKey: true,
+			},
+		},
+		{name: "absent", want: map[string]any{}},
+		{name: "malformed trace", header: "xyz/74;o=1", want: map[string]any{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := make(http.Header)
+			if tt.header != "" {
+				h.Set(CloudTraceContextHeader, tt.header)
+			}
+			fields := TraceContextFromHeader(h, "my-project")
+			assertJSON(t, "trace", traceFieldsOf(t, fields), tt.want)
+		})
+	}
+}
+
+func TestTraceResource(t *testing.T) {
+	tests := []struct {
+		name     string
+		fullName string
+		want     map[string]any
+	}{
+		{
+			name:     "resource name",
+			fullName: "projects/my-project/traces/" + testTraceID,
+			want:     map[string]any{TraceKey: "projects/my-project/traces/" + testTraceID},
+		},
+		{name: "bare trace ID", fullName: testTraceID, want: map[string]any{}},
+		{name: "empty", fullName: "", want: map[string]any{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields := []zap.Field{TraceResource(tt.fullName)}
+			assertJSON(t, "trace", traceFieldsOf(t, fields), tt.want)
+		})
+	}
+}
+
+func TestTraceContextFromGRPCBin(t *testing.T) {
+	traceID, _ := hex.DecodeString(testTraceID)
+	spanID, _ := hex.DecodeString(testSpanID)
+	// bin returns the binary trace context of version with fields.
+	bin := func(version byte, fields ...[]byte) string {
+		b := []byte{version}
+		for _, f := range fields {
+			b = append(b, f...)
+		}
+		return string(b)
+	}
+	traceField := append([]byte{0}, traceID...)
+	spanField := append([]byte{1}, spanID...)
+
+	tests := []struct {
+		name string
+		md   map[string][]string
+		want map[string]any
+	}{
+		{
+			name: "sampled",
+			md:   map[string][]string{grpcTraceBinKey: {bin(0, traceField, spanField, []byte{2, 1})}},
+			want: map[string]any{
+				TraceKey:        "projects/my-project/traces/" + testTraceID,
+				SpanIDKey:       testSpanID,
+				TraceSampledKey: true,
+			},
+		},
+		{
+			name: "trace only",
+			md:   map[string][]string{grpcTraceBinKey: {bin(0, traceField)}},
+			want: map[string]any{
+				TraceKey:        "projects/my-project/traces/" + testTraceID,
+				TraceSampledKey: false,
+			},
+		},
+		{
+			name: "unknown field after trace",
+			md:   map[string][]string{grpcTraceBinKey: {bin(0, traceField, []byte{9, 1, 2, 3}, spanField)}},
+			want: map[string]any{
+				TraceKey:        "projects/my-project/traces/" + testTraceID,
+				TraceSampledKey: false,
+			},
+		},
+		{
+			name: "unknown field before trace",
+			md:   map[string][]string{grpcTraceBinKey: {bin(0, []byte{9, 1, 2, 3}, traceField)}},
+			want: map[string]any{},
+		},
+		{
+			name: "truncated trace",
+			md:   map[string][]string{grpcTraceBinKey: {bin(0, traceField[:9])}},
+			want: map[string]any{},
+		},
+		{
+			name: "truncated span",
+			md:   map[string][]string{grpcTraceBinKey: {bin(0, traceField, spanField[:4])}},
+			want: map[string]any{},
+		},
+		{
+			name: "non-zero version",
+			md:   map[string][]string{grpcTraceBinKey: {bin(1, traceField, spanField)}},
+			want: map[string]any{},
+		},
+		{
+			name: "zero trace",
+			md:   map[string][]string{grpcTraceBinKey: {bin(0, make([]byte, 17), spanField)}},
+			want: map[string]any{},
+		},
+		{name: "empty", md: map[string][]string{grpcTraceBinKey: {""}}, want: map[string]any{}},
+		{name: "absent", md: map[string][]string{}, want: map[string]any{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields := TraceContextFromGRPCBin(tt.md, "my-project")
+			assertJSON(t, "trace", traceFieldsOf(t, fields), tt.want)
+		})
+	}
+}
+
+func TestTraceContextFromB3(t *testing.T) {
+	sampled := map[string]any{
+		TraceKey:        "projects/my-project/traces/" + testTraceID,
+		SpanIDKey:       testSpanID,
+		TraceSampledKey: true,
+	}
+	tests := []struct {
+		name   string
+		header http.Header
+		want   map[string]any
+	}{
+		{name: "single header", header: http.Header{"B3": {testTraceID + "-" + testSpanID + "-1"}}, want: sampled},
+		{
+			name:   "single header with parent",
+			header: http.Header{"B3": {testTraceID + "-" + testSpanID + "-d-0000000000000001"}},
+			want:   sampled,
+		},
+		{
+			name:   "single header 64-bit trace",
+			header: http.Header{"B3": {"2f19b7cfb3214824-" + testSpanID}},
+			want: map[string]any{
+				TraceKey:        "projects/my-project/traces/00000000000000002f19b7cfb3214824",
+				SpanIDKey:       testSpanID,
+				TraceSampledKey: false,
+			},
+		},
+		{name: "sampling state only", header: http.Header{"B3": {"0"}}, want: map[string]any{}},
+		{
+			name: "multiple headers",
+			header: http.Header{
+				"X-B3-Traceid": {"06796866738C859F2F19B7CFB3214824"},
+				"X-B3-Spanid":  {testSpanID},
+				"X-B3-Sampled": {"true"},
+			},
+			want: sampled,
+		},
+		{
+			name: "multiple headers 64-bit trace",
+			header: http.Header{
+				"X-B3-Traceid": {"2f19b7cfb3214824"},
+				"X-B3-Spanid":  {testSpanID},
+				"X-B3-Sampled": {"0"},
+			},
+			want: map[string]any{
+				TraceKey:        "projects/my-project/traces/00000000000000002f19b7cfb3214824",
+				SpanIDKey:       testSpanID,
+				TraceSampledKey: false,
+			},
+		},
+		{
+			name: "debug flag",
+			header: http.Header{
+				"X-B3-Traceid": {testTraceID},
+				"X-B3-Spanid":  {testSpanID},
+				"X-B3-Sampled": {"0"},
+				"X-B3-Flags":   {"1"},
+			},
+			want: sampled,
+		},
+		{
+			name: "single header wins",
+			header: http.Header{
+				"B3":           {testTraceID + "-" + testSpanID + "-1"},
+				"X-B3-Traceid": {"00000000000000000000000000000001"},
+				"X-B3-Spanid":  {"0000000000000002"},
+				"X-B3-Sampled": {"0"},
+			},
+			want: sampled,
+		},
+		{
+			name: "malformed single header",
+			header: http.Header{
+				"B3":           {"0"},
+				"X-B3-Traceid": {testTraceID},
+				"X-B3-Spanid":  {testSpanID},
+				"X-B3-Sampled": {"1"},
+			},
+			want: sampled,
+		},
+		{name: "malformed trace", header: http.Header{"X-B3-Traceid": {"xyz"}}, want: map[string]any{}},
+		{name: "absent", header: http.Header{}, want: map[string]any{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields := TraceContextFromB3(tt.header, "my-project")
+			assertJSON(t, "trace", traceFieldsOf(t, fields), tt.want)
+		})
+	}
+}
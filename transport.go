@@ -0,0 +1,101 @@
+package zapcloudlogging
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type loggingTransport struct {
+	base      http.RoundTripper
+	logger    *zap.Logger
+	projectID func() string
+}
+
+// NewLoggingTransport returns a http.RoundTripper that sends requests with base and logs each of them
+// with logger, with the method, URL, status and latency set as the httpRequest of the entry.
+// If base is nil, http.DefaultTransport is used.
+//
+// If the request context carries the trace of an incoming request, stored by Middleware,
+// the trace is forwarded with the X-Cloud-Trace-Context header unless the request has the header,
+// and the entry is associated with the trace.
+// Likewise, the correlation ID stored by Middleware or EnsureCorrelationID is forwarded with the X-Correlation-ID header.
+//
+// Responses with 5xx statuses and failed requests are logged at ERROR, 4xx at WARNING and others at INFO.
+// The password and the values of the query are redacted from the logged URL, since they may carry credentials.
+func NewLoggingTransport(base http.RoundTripper, logger *zap.Logger) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &loggingTransport{
+		base:      base,
+		logger:    logger,
+		projectID: lazyProjectID(),
+	}
+}
+
+func (t *loggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var fields []zap.Field
+	if tc, ok := traceContextFrom(req.Context()); ok {
+		if req.Header.Get(CloudTraceContextHeader) == "" {
+			// A RoundTripper must not modify the request.
+			req = req.Clone(req.Context())
+			req.Header.Set(CloudTraceContextHeader, tc.header())
+		}
+		fields = tc.fields(t.projectID())
+	}
+	if id, ok := correlationIDFrom(req.Context()); ok && req.Header.Get(CorrelationIDHeader) == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set(CorrelationIDHeader, id)
+	}
+
+	start := time.Now()
+	res, err := t.base.RoundTrip(req)
+	u := redactedURL(req.URL)
+	hr := &HTTPRequest{
+		RequestMethod: req.Method,
+		RequestURL:    u,
+		RequestSize:   req.ContentLength,
+		UserAgent:     req.UserAgent(),
+		Latency:       time.Since(start),
+		Protocol:      req.Proto,
+	}
+
+	var level zapcore.Level
+	if err != nil {
+		level = zap.ErrorLevel
+		fields = append(fields, zap.Error(err))
+	} else {
+		hr.Status = res.StatusCode
+		hr.ResponseSize = res.ContentLength
+		hr.Protocol = res.Proto
+		level = statusLevel(res.StatusCode)
+	}
+
+	if ce := t.logger.Check(level, req.Method+" "+u); ce != nil {
+		ce.Write(append(fields, HTTPRequestField(hr))...)
+	}
+	return res, err
+}
+
+// redactedQueryValue replaces the values of the query redacted by redactedURL, like the password of url.URL.Redacted.
+const redactedQueryValue = "xxxxx"
+
+// redactedURL returns u with the password and the values of the query redacted.
+func redactedURL(u *url.URL) string {
+	if u.RawQuery == "" {
+		return u.Redacted()
+	}
+	r := *u
+	q, _ := url.ParseQuery(u.RawQuery)
+	for _, vs := range q {
+		for i := range vs {
+			vs[i] = redactedQueryValue
+		}
+	}
+	r.RawQuery = q.Encode()
+	return r.Redacted()
+}
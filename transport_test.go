@@ -0,0 +1,126 @@
+package zapcloudlogging
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLoggingTransport(t *testing.T) {
+	tc := traceContext{traceID: "105445aa7843bc8bf206b12000100000", spanID: "0000000000000001", sampled: true}
+
+	tests := []struct {
+		name string
+		ctx  func(context.Context) context.Context
+		// header is the header set to the request.
+		header       http.Header
+		status       int
+		path         string
+		wantSeverity string
+		wantURL      string
+		wantTrace    string
+		wantCorrID   string
+	}{
+		{
+			name:         "no trace",
+			ctx:          func(ctx context.Context) context.Context { return ctx },
+			status:       http.StatusOK,
+			path:         "/ok",
+			wantSeverity: "INFO",
+			wantURL:      "/ok",
+		},
+		{
+			name: "trace and correlation ID",
+			ctx: func(ctx context.Context) context.Context {
+				return withCorrelationID(withTraceContext(ctx, tc), "corr-1")
+			},
+			status:       http.StatusNotFound,
+			path:         "/missing",
+			wantSeverity: "WARNING",
+			wantURL:      "/missing",
+			wantTrace:    "105445aa7843bc8bf206b12000100000/1;o=1",
+			wantCorrID:   "corr-1",
+		},
+		{
+			name: "headers set by the request",
+			ctx: func(ctx context.Context) context.Context {
+				return withCorrelationID(withTraceContext(ctx, tc), "corr-1")
+			},
+			header: http.Header{
+				CloudTraceContextHeader: {"00000000000000000000000000000001/2;o=0"},
+				CorrelationIDHeader:     {"corr-2"},
+			},
+			status:       http.StatusInternalServerError,
+			path:         "/fail",
+			wantSeverity: "ERROR",
+			wantURL:      "/fail",
+			wantTrace:    "00000000000000000000000000000001/2;o=0",
+			wantCorrID:   "corr-2",
+		},
+		{
+			name:         "credentials redacted",
+			ctx:          func(ctx context.Context) context.Context { return ctx },
+			status:       http.StatusOK,
+			path:         "/q?token=abc&page=2",
+			wantSeverity: "INFO",
+			wantURL:      "/q?page=xxxxx&token=xxxxx",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got http.Header
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.Header.Clone()
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			logger, sink := newTestLogger(t)
+			client := &http.Client{Transport: NewLoggingTransport(nil, logger)}
+
+			u := strings.Replace(srv.URL, "http://", "http://user:secret@", 1) + tt.path
+			req, err := http.NewRequestWithContext(tt.ctx(context.Background()), http.MethodGet, u, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for k, vs := range tt.header {
+				req.Header[k] = vs
+			}
+			res, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("Do() = %v", err)
+			}
+			res.Body.Close()
+
+			if v := got.Get(CloudTraceContextHeader); v != tt.wantTrace {
+				t.Errorf("forwarded %s = %q, want %q", CloudTraceContextHeader, v, tt.wantTrace)
+			}
+			if v := got.Get(CorrelationIDHeader); v != tt.wantCorrID {
+				t.Errorf("forwarded %s = %q, want %q", CorrelationIDHeader, v, tt.wantCorrID)
+			}
+
+			out := sink.String()
+			if strings.Contains(out, "secret") || strings.Contains(out, "abc") {
+				t.Errorf("the entry has the credentials: %s", out)
+			}
+			entries := sink.entries(t)
+			if len(entries) != 1 {
+				t.Fatalf("got %d entries, want 1", len(entries))
+			}
+			e := entries[0]
+			wantURL := strings.Replace(srv.URL, "http://", "http://user:xxxxx@", 1) + tt.wantURL
+			if e[SeverityKey] != tt.wantSeverity {
+				t.Errorf("severity = %v, want %s", e[SeverityKey], tt.wantSeverity)
+			}
+			if msg := "GET " + wantURL; e[MessageKey] != msg {
+				t.Errorf("message = %v, want %s", e[MessageKey], msg)
+			}
+			hr, _ := e[HTTPRequestKey].(map[string]any)
+			if hr["requestUrl"] != wantURL {
+				t.Errorf("httpRequest.requestUrl = %v, want %s", hr["requestUrl"], wantURL)
+			}
+		})
+	}
+}